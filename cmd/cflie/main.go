@@ -6,13 +6,17 @@ import (
 	"os"
 
 	"github.com/samofly/cflie/pkg/config"
+	"github.com/samofly/cflie/pkg/console"
 	"github.com/samofly/cflie/pkg/dump"
 	"github.com/samofly/cflie/pkg/flash"
+	logcmd "github.com/samofly/cflie/pkg/log"
 	"github.com/samofly/cflie/pkg/ls"
+	"github.com/samofly/cflie/pkg/param"
 	"github.com/samofly/cflie/pkg/play"
 	"github.com/samofly/cflie/pkg/record"
 	"github.com/samofly/cflie/pkg/scan"
 	"github.com/samofly/cflie/pkg/spin"
+	"github.com/samofly/cflie/pkg/status"
 )
 
 func main() {
@@ -27,12 +31,18 @@ func main() {
 	switch cmd {
 	case "config":
 		config.Main()
+	case "console":
+		console.Main()
 	case "dump":
 		dump.Main()
 	case "flash":
 		flash.Main()
+	case "log":
+		logcmd.Main()
 	case "ls":
 		ls.Main()
+	case "param":
+		param.Main()
 	case "play":
 		play.Main()
 	case "record":
@@ -41,6 +51,8 @@ func main() {
 		scan.Main()
 	case "spin":
 		spin.Main()
+	case "status":
+		status.Main()
 	default:
 		log.Fatalf("Unknown command %s", cmd)
 	}
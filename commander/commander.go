@@ -0,0 +1,174 @@
+// Package commander sends flight setpoints to Crazyflie.
+//
+// Crazyflie firmware expects setpoints to arrive continuously; Commander
+// resends the last setpoint at a fixed rate and falls back to a zero
+// setpoint (motors off) if the caller stops updating it.
+package commander
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/samofly/cflie/crtp"
+)
+
+const (
+	DefaultPeriod  = 10 * time.Millisecond
+	DefaultTimeout = 500 * time.Millisecond
+
+	// MaxThrust is the highest thrust accepted by Commander.
+	// Values above it saturate the motors and are most likely an error.
+	MaxThrust = 60000
+)
+
+var ErrClosed = fmt.Errorf("Commander is closed")
+
+// Setpoint is a target attitude (in degrees) and thrust.
+type Setpoint struct {
+	Roll   float32
+	Pitch  float32
+	Yaw    float32
+	Thrust uint16
+}
+
+// Validate checks that the setpoint is safe to send.
+func (sp Setpoint) Validate() error {
+	if sp.Thrust > MaxThrust {
+		return fmt.Errorf("Thrust is out of range: %d, max: %d", sp.Thrust, MaxThrust)
+	}
+	return nil
+}
+
+// Packet encodes the setpoint as a CRTP commander packet.
+func (sp Setpoint) Packet() *crtp.Packet {
+	var buf bytes.Buffer
+	err := binary.Write(&buf, binary.LittleEndian, sp)
+	if err != nil {
+		panic(fmt.Sprintf("binary.Write: %v", err))
+	}
+	return crtp.NewPacket(crtp.PortCommander, 0, buf.Bytes())
+}
+
+type Commander struct {
+	conn    *crtp.Conn
+	period  time.Duration
+	timeout time.Duration
+
+	mu      sync.Mutex
+	sp      Setpoint
+	updated time.Time
+	closed  bool
+	// The reason why setpoints are no longer sent
+	err  error
+	done chan bool
+}
+
+// New starts sending setpoints to conn every period.
+// If the setpoint is not updated within timeout, Commander sends
+// a zero setpoint until the next Set call.
+// Zero period or timeout means DefaultPeriod and DefaultTimeout respectively.
+func New(conn *crtp.Conn, period, timeout time.Duration) *Commander {
+	if period == 0 {
+		period = DefaultPeriod
+	}
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+	c := &Commander{
+		conn:    conn,
+		period:  period,
+		timeout: timeout,
+		updated: time.Now(),
+		done:    make(chan bool),
+	}
+	go c.run()
+	return c
+}
+
+// Set updates the current setpoint. Once a setpoint could not be sent,
+// Commander stops and Set returns the send error.
+func (c *Commander) Set(sp Setpoint) error {
+	if err := sp.Validate(); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return c.err
+	}
+	if c.closed {
+		return ErrClosed
+	}
+	c.sp = sp
+	c.updated = time.Now()
+	return nil
+}
+
+// Stop sets a zero setpoint, which turns the motors off.
+func (c *Commander) Stop() error {
+	return c.Set(Setpoint{})
+}
+
+// Err returns the error which has stopped Commander, or nil if it's running or closed.
+func (c *Commander) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// Done returns a channel which is closed once Commander stops sending setpoints,
+// either on Close or because of an error, see Err.
+func (c *Commander) Done() <-chan bool {
+	return c.done
+}
+
+// Close sends a zero setpoint and stops sending setpoints.
+// It does not close the underlying connection.
+func (c *Commander) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return ErrClosed
+	}
+	c.closed = true
+	c.mu.Unlock()
+	<-c.done
+	if err := c.Err(); err != nil {
+		return err
+	}
+	return c.conn.Send(Setpoint{}.Packet())
+}
+
+func (c *Commander) current() (sp Setpoint, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return Setpoint{}, false
+	}
+	if time.Now().Sub(c.updated) > c.timeout {
+		// No setpoint for too long, stop the motors
+		c.sp = Setpoint{}
+	}
+	return c.sp, true
+}
+
+func (c *Commander) run() {
+	defer close(c.done)
+	ticker := time.NewTicker(c.period)
+	defer ticker.Stop()
+	for _ = range ticker.C {
+		sp, ok := c.current()
+		if !ok {
+			return
+		}
+		if err := c.conn.Send(sp.Packet()); err != nil {
+			c.mu.Lock()
+			c.err = fmt.Errorf("Unable to send a setpoint: %v", err)
+			c.mu.Unlock()
+			return
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package commander
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/samofly/cflie"
+	"github.com/samofly/cflie/crtp"
+)
+
+func TestSetpointPacket(t *testing.T) {
+	sp := Setpoint{Roll: 0, Pitch: 0, Yaw: 0, Thrust: 30000}
+	want := []byte{60, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 48, 117}
+	if got := sp.Packet().Bytes(); !bytes.Equal(got, want) {
+		t.Errorf("Unexpected packet. Want: %v, got: %v", want, got)
+	}
+	if err := (Setpoint{Thrust: MaxThrust + 1}).Validate(); err == nil {
+		t.Errorf("Validate must fail for thrust > MaxThrust")
+	}
+}
+
+func TestSafetyStop(t *testing.T) {
+	sendChan := make(chan []byte, 100)
+	conn := crtp.NewConn(&cflie.Endpoint{RecvChan: make(chan []byte), SendChan: sendChan})
+	c := New(conn, time.Millisecond, 20*time.Millisecond)
+	if err := c.Set(Setpoint{Thrust: 30000}); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	stop := Setpoint{}.Packet().Bytes()
+	deadline := time.After(time.Second)
+	for sawThrust := false; ; {
+		select {
+		case p := <-sendChan:
+			if !bytes.Equal(p, stop) {
+				sawThrust = true
+				continue
+			}
+			if sawThrust {
+				if err := c.Close(); err != nil {
+					t.Fatalf("Close: %v", err)
+				}
+				return
+			}
+		case <-deadline:
+			t.Fatalf("Commander did not send a safety stop")
+		}
+	}
+}
+
+func TestSendError(t *testing.T) {
+	conn := crtp.NewConn(&cflie.Endpoint{RecvChan: make(chan []byte), SendChan: make(chan []byte, 100)})
+	c := New(conn, time.Millisecond, 0)
+	conn.Close()
+	select {
+	case <-c.Done():
+	case <-time.After(time.Second):
+		t.Fatalf("Commander must stop once the connection is closed")
+	}
+	if c.Err() == nil {
+		t.Errorf("Err must be set")
+	}
+	if err := c.Set(Setpoint{Thrust: 30000}); err == nil {
+		t.Errorf("Set must fail once setpoints can't be sent")
+	}
+	if err := c.Close(); err == nil {
+		t.Errorf("Close must fail once setpoints can't be sent")
+	}
+}
@@ -0,0 +1,74 @@
+// Package console reads the text which Crazyflie firmware prints with consolePrintf.
+//
+// The text is sent to the console port in packets of up to crtp.MaxPayload bytes,
+// so a single line may be split across several packets.
+package console
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/samofly/cflie/crtp"
+)
+
+const Channel = 0
+
+type Reader struct {
+	conn    *crtp.Conn
+	sub     <-chan *crtp.Packet
+	pending []byte
+}
+
+// NewReader subscribes to the console port of conn.
+func NewReader(conn *crtp.Conn) *Reader {
+	return &Reader{conn: conn, sub: conn.Subscribe(crtp.PortConsole, Channel)}
+}
+
+// fill waits for the next console packet. It returns io.EOF once the connection is closed.
+func (r *Reader) fill() error {
+	pk, ok := <-r.sub
+	if !ok {
+		return io.EOF
+	}
+	r.pending = append(r.pending, pk.Data...)
+	return nil
+}
+
+// Read implements io.Reader. It returns the console text as soon as it arrives.
+func (r *Reader) Read(p []byte) (n int, err error) {
+	for len(r.pending) == 0 {
+		if err = r.fill(); err != nil {
+			return
+		}
+	}
+	n = copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return
+}
+
+// ReadLine returns the next complete line without the trailing newline.
+// If the connection is closed in the middle of a line, the incomplete line
+// is returned first, and io.EOF is returned by the next call.
+func (r *Reader) ReadLine() (line string, err error) {
+	for {
+		if i := bytes.IndexByte(r.pending, '\n'); i >= 0 {
+			line = string(r.pending[:i])
+			r.pending = r.pending[i+1:]
+			return
+		}
+		if err = r.fill(); err != nil {
+			if len(r.pending) > 0 {
+				line = string(r.pending)
+				r.pending = nil
+				return line, nil
+			}
+			return
+		}
+	}
+}
+
+// Close stops receiving console packets. It does not close the connection.
+func (r *Reader) Close() error {
+	r.conn.Unsubscribe(r.sub)
+	return nil
+}
@@ -0,0 +1,36 @@
+package console
+
+import (
+	"io"
+	"testing"
+
+	"github.com/samofly/cflie"
+	"github.com/samofly/cflie/crtp"
+)
+
+func TestReadLine(t *testing.T) {
+	recvChan := make(chan []byte)
+	conn := crtp.NewConn(&cflie.Endpoint{RecvChan: recvChan, SendChan: make(chan []byte)})
+	r := NewReader(conn)
+	go func() {
+		for _, s := range []string{"Crazyflie is up and ", "running!\nBuild: 3", "2\nbroken"} {
+			recvChan <- crtp.NewPacket(crtp.PortConsole, Channel, []byte(s)).Bytes()
+			// Packets from other ports must be ignored
+			recvChan <- crtp.NewPacket(crtp.PortLog, 2, []byte("noise")).Bytes()
+		}
+		close(recvChan)
+	}()
+
+	for _, want := range []string{"Crazyflie is up and running!", "Build: 32", "broken"} {
+		line, err := r.ReadLine()
+		if err != nil {
+			t.Fatalf("ReadLine: %v", err)
+		}
+		if line != want {
+			t.Errorf("ReadLine: want %q, got %q", want, line)
+		}
+	}
+	if _, err := r.ReadLine(); err != io.EOF {
+		t.Errorf("ReadLine: want io.EOF, got %v", err)
+	}
+}
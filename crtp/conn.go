@@ -0,0 +1,172 @@
+package crtp
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/samofly/cflie"
+)
+
+// AnyChannel subscribes to all channels of a port.
+const AnyChannel = 0xFF
+
+// Size of a subscriber queue. If a subscriber does not keep up,
+// extra packets are dropped, see Conn.Dropped.
+const subscriberQueueSize = 32
+
+var ErrClosed = fmt.Errorf("CRTP connection is closed")
+
+type subKey struct {
+	port Port
+	ch   uint8
+}
+
+// Conn multiplexes CRTP ports and channels over a single Endpoint.
+// Incoming packets are routed to subscribers of the matching port/channel pair;
+// packets nobody is subscribed to are dropped.
+type Conn struct {
+	ep *cflie.Endpoint
+
+	mu   sync.Mutex
+	subs map[subKey][]chan *Packet
+	done bool
+	// Packets dropped because subscribers did not keep up
+	dropped int
+
+	// Guards SendChan, so that it's not closed while someone is sending to it.
+	sendMu sync.Mutex
+	closed bool
+}
+
+// NewConn starts routing packets received from ep.
+// Conn takes the ownership of ep: it must not be used directly after that.
+func NewConn(ep *cflie.Endpoint) *Conn {
+	c := &Conn{
+		ep:   ep,
+		subs: make(map[subKey][]chan *Packet),
+	}
+	go c.run()
+	return c
+}
+
+// Subscribe returns a channel that receives all packets sent by Crazyflie
+// to the specified port and channel. Use AnyChannel to receive packets from all channels of the port.
+// The channel is closed when the connection is closed or on Unsubscribe.
+func (c *Conn) Subscribe(port Port, ch uint8) <-chan *Packet {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	sub := make(chan *Packet, subscriberQueueSize)
+	if c.done {
+		close(sub)
+		return sub
+	}
+	key := subKey{port, ch}
+	c.subs[key] = append(c.subs[key], sub)
+	return sub
+}
+
+// Unsubscribe stops delivering packets to sub and closes it.
+func (c *Conn) Unsubscribe(sub <-chan *Packet) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, list := range c.subs {
+		for i, cur := range list {
+			if cur != sub {
+				continue
+			}
+			close(cur)
+			list = append(list[:i], list[i+1:]...)
+			if len(list) == 0 {
+				delete(c.subs, key)
+			} else {
+				c.subs[key] = list
+			}
+			return
+		}
+	}
+}
+
+// Send sends a packet to Crazyflie.
+func (c *Conn) Send(pk *Packet) error {
+	if len(pk.Data) > MaxPayload {
+		return fmt.Errorf("Payload is too large: %d bytes, max: %d", len(pk.Data), MaxPayload)
+	}
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+	if c.closed || c.isDone() {
+		return ErrClosed
+	}
+	select {
+	case c.ep.SendChan <- pk.Bytes():
+	case <-c.ep.Done():
+		return ErrClosed
+	}
+	return nil
+}
+
+// Close closes the underlying endpoint. All subscriptions are closed
+// once the endpoint stops delivering packets.
+func (c *Conn) Close() error {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+	if c.closed {
+		return ErrClosed
+	}
+	c.closed = true
+	return c.ep.Close()
+}
+
+// Dropped returns the number of packets which have not been delivered
+// to subscribers because their queues were full. Packets dropped by
+// the endpoint itself are counted in its LinkStats.
+func (c *Conn) Dropped() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.dropped
+}
+
+// Err returns the reason why the underlying endpoint is down, or nil if it's up.
+func (c *Conn) Err() error {
+	return c.ep.Err()
+}
+
+func (c *Conn) isDone() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.done
+}
+
+func (c *Conn) run() {
+	for p := range c.ep.RecvChan {
+		pk, err := Parse(p)
+		if err != nil {
+			// Empty ACK, nothing to route
+			continue
+		}
+		c.route(pk)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, list := range c.subs {
+		for _, sub := range list {
+			close(sub)
+		}
+	}
+	c.subs = make(map[subKey][]chan *Packet)
+	c.done = true
+}
+
+func (c *Conn) route(pk *Packet) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range []subKey{{pk.Port, pk.Channel}, {pk.Port, AnyChannel}} {
+		for _, sub := range c.subs[key] {
+			// If the subscriber is too slow, just drop the packet.
+			select {
+			case sub <- pk:
+			default:
+				c.dropped++
+			}
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package crtp
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/samofly/cflie"
+)
+
+func TestHeader(t *testing.T) {
+	tests := []struct {
+		port Port
+		ch   uint8
+		want byte
+	}{
+		{PortConsole, 0, 0x0C},
+		{PortCommander, 0, 60},
+		{PortParam, 1, 0x2D},
+		{PortLog, 2, 0x5E},
+		{PortLink, 3, 0xFF},
+	}
+	for _, tt := range tests {
+		h := Header(tt.port, tt.ch)
+		if h != tt.want {
+			t.Errorf("Header(%s, %d): want 0x%02X, got 0x%02X", tt.port, tt.ch, tt.want, h)
+		}
+		port, ch, link := ParseHeader(h)
+		if port != tt.port || ch != tt.ch || link != 3 {
+			t.Errorf("ParseHeader(0x%02X): want %s/%d/3, got %s/%d/%d", h, tt.port, tt.ch, port, ch, link)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	if _, err := Parse(nil); err != ErrEmptyPacket {
+		t.Errorf("Parse(nil): want ErrEmptyPacket, got %v", err)
+	}
+	pk, err := Parse([]byte{0x5E, 1, 2, 3})
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if pk.Port != PortLog || pk.Channel != 2 || !bytes.Equal(pk.Data, []byte{1, 2, 3}) {
+		t.Errorf("Unexpected packet: %v", pk)
+	}
+	if got := pk.Bytes(); !bytes.Equal(got, []byte{0x5E, 1, 2, 3}) {
+		t.Errorf("Bytes: want [94 1 2 3], got %v", got)
+	}
+}
+
+func recvPacket(t *testing.T, sub <-chan *Packet) *Packet {
+	select {
+	case pk := <-sub:
+		return pk
+	case <-time.After(time.Second):
+		t.Fatalf("Timed out waiting for a packet")
+	}
+	return nil
+}
+
+func TestConnRouting(t *testing.T) {
+	recvChan := make(chan []byte)
+	sendChan := make(chan []byte, 1)
+	c := NewConn(&cflie.Endpoint{RecvChan: recvChan, SendChan: sendChan})
+
+	logSub := c.Subscribe(PortLog, 2)
+	anyLogSub := c.Subscribe(PortLog, AnyChannel)
+	consoleSub := c.Subscribe(PortConsole, 0)
+
+	recvChan <- []byte{}
+	recvChan <- []byte{0x5E, 42}
+	recvChan <- []byte{0x0C, 'h', 'i'}
+
+	if pk := recvPacket(t, logSub); pk.Data[0] != 42 {
+		t.Errorf("logSub: unexpected packet %v", pk)
+	}
+	if pk := recvPacket(t, anyLogSub); pk.Data[0] != 42 {
+		t.Errorf("anyLogSub: unexpected packet %v", pk)
+	}
+	if pk := recvPacket(t, consoleSub); string(pk.Data) != "hi" {
+		t.Errorf("consoleSub: unexpected packet %v", pk)
+	}
+
+	if err := c.Send(NewPacket(PortCommander, 0, []byte{1})); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if p := <-sendChan; !bytes.Equal(p, []byte{60, 1}) {
+		t.Errorf("Send: unexpected bytes %v", p)
+	}
+
+	c.Unsubscribe(consoleSub)
+	if _, ok := <-consoleSub; ok {
+		t.Errorf("consoleSub must be closed after Unsubscribe")
+	}
+
+	close(recvChan)
+	if _, ok := <-logSub; ok {
+		t.Errorf("logSub must be closed after the endpoint is closed")
+	}
+	if err := c.Send(NewPacket(PortCommander, 0, nil)); err != ErrClosed {
+		t.Errorf("Send after close: want ErrClosed, got %v", err)
+	}
+}
+
+func TestConnDropped(t *testing.T) {
+	recvChan := make(chan []byte)
+	c := NewConn(&cflie.Endpoint{RecvChan: recvChan, SendChan: make(chan []byte)})
+	slow := c.Subscribe(PortLog, 2)
+	fast := c.Subscribe(PortConsole, 0)
+	for i := 0; i < subscriberQueueSize+5; i++ {
+		recvChan <- []byte{0x5E, byte(i)}
+	}
+	// Once the console packet is delivered, the log packets are routed
+	recvChan <- []byte{0x0C, 'x'}
+	recvPacket(t, fast)
+	if n := c.Dropped(); n != 5 {
+		t.Errorf("Dropped: want 5, got %d", n)
+	}
+	if n := len(slow); n != subscriberQueueSize {
+		t.Errorf("Want %d queued packets, got %d", subscriberQueueSize, n)
+	}
+	close(recvChan)
+}
@@ -0,0 +1,73 @@
+// Package crtptest provides a fake Crazyflie for testing CRTP clients.
+package crtptest
+
+import (
+	"encoding/binary"
+
+	"github.com/samofly/cflie"
+	"github.com/samofly/cflie/crtp"
+	"github.com/samofly/cflie/toc"
+)
+
+// Flie is a fake Crazyflie. It serves TOC requests of the ports listed in TOC
+// and passes all the other packets to Handle.
+type Flie struct {
+	// TOCs served on toc.Channel, by port
+	TOC map[crtp.Port]*toc.TOC
+	// Handle returns the replies to a packet, if any. It may be nil.
+	Handle func(pk *crtp.Packet) []*crtp.Packet
+}
+
+// Conn starts the fake Crazyflie and returns a connection to it.
+// The fake stops once the connection is closed.
+func (f *Flie) Conn() *crtp.Conn {
+	sendChan := make(chan []byte)
+	recvChan := make(chan []byte, 16)
+	go f.serve(sendChan, recvChan)
+	return crtp.NewConn(&cflie.Endpoint{RecvChan: recvChan, SendChan: sendChan})
+}
+
+func (f *Flie) serve(sendChan <-chan []byte, recvChan chan<- []byte) {
+	defer close(recvChan)
+	for p := range sendChan {
+		pk, err := crtp.Parse(p)
+		if err != nil {
+			continue
+		}
+		var replies []*crtp.Packet
+		if t, ok := f.TOC[pk.Port]; ok && pk.Channel == toc.Channel {
+			replies = tocReply(t, pk)
+		} else if f.Handle != nil {
+			replies = f.Handle(pk)
+		}
+		for _, r := range replies {
+			recvChan <- r.Bytes()
+		}
+	}
+}
+
+// tocReply replies to GetInfo and GetItem the same way as Crazyflie firmware does.
+func tocReply(t *toc.TOC, pk *crtp.Packet) []*crtp.Packet {
+	if len(pk.Data) == 0 {
+		return nil
+	}
+	var reply []byte
+	switch pk.Data[0] {
+	case toc.CMD_GET_INFO:
+		reply = []byte{toc.CMD_GET_INFO, byte(len(t.Elements)), 0, 0, 0, 0}
+		binary.LittleEndian.PutUint32(reply[2:], t.CRC)
+		reply = append(reply, t.Info...)
+	case toc.CMD_GET_ITEM:
+		if len(pk.Data) < 2 {
+			return nil
+		}
+		e, ok := t.ByID(pk.Data[1])
+		if !ok {
+			return nil
+		}
+		reply = append([]byte{toc.CMD_GET_ITEM, e.ID, e.Type}, e.Group+"\x00"+e.Name+"\x00"...)
+	default:
+		return nil
+	}
+	return []*crtp.Packet{crtp.NewPacket(pk.Port, toc.Channel, reply)}
+}
@@ -0,0 +1,92 @@
+// Package crtp implements the CRTP (Crazy RealTime Protocol) packet layer
+// on top of cflie.Endpoint.
+//
+// Every CRTP packet starts with a header byte:
+//
+//	bits 7..4: port
+//	bits 3..2: link bits (always set for host-originated packets)
+//	bits 1..0: channel
+//
+// followed by up to MaxPayload bytes of data.
+package crtp
+
+import "fmt"
+
+type Port uint8
+
+const (
+	PortConsole   Port = 0x0
+	PortParam     Port = 0x2
+	PortCommander Port = 0x3
+	PortLog       Port = 0x5
+	PortDebug     Port = 0xE
+	PortLink      Port = 0xF
+
+	MaxPort    = 0xF
+	MaxChannel = 0x3
+	MaxPayload = 30
+
+	linkBits = 0x0C
+)
+
+func (port Port) String() string {
+	switch port {
+	case PortConsole:
+		return "Console"
+	case PortParam:
+		return "Param"
+	case PortCommander:
+		return "Commander"
+	case PortLog:
+		return "Log"
+	case PortDebug:
+		return "Debug"
+	case PortLink:
+		return "Link"
+	}
+	return fmt.Sprintf("Port:#%d", uint8(port))
+}
+
+var ErrEmptyPacket = fmt.Errorf("Empty CRTP packet")
+
+// Header builds a CRTP header byte for the specified port and channel.
+func Header(port Port, ch uint8) byte {
+	return byte(port&MaxPort)<<4 | linkBits | ch&MaxChannel
+}
+
+// ParseHeader splits a CRTP header byte into port, channel and link bits.
+func ParseHeader(h byte) (port Port, ch uint8, link uint8) {
+	return Port(h >> 4), h & MaxChannel, (h >> 2) & 0x3
+}
+
+type Packet struct {
+	Port    Port
+	Channel uint8
+	Data    []byte
+}
+
+func NewPacket(port Port, ch uint8, data []byte) *Packet {
+	return &Packet{Port: port, Channel: ch, Data: data}
+}
+
+// Parse decodes a packet received from Endpoint.RecvChan.
+// The data of the returned packet shares memory with p.
+func Parse(p []byte) (pk *Packet, err error) {
+	if len(p) == 0 {
+		return nil, ErrEmptyPacket
+	}
+	port, ch, _ := ParseHeader(p[0])
+	return &Packet{Port: port, Channel: ch, Data: p[1:]}, nil
+}
+
+// Bytes encodes the packet so that it could be sent to Endpoint.SendChan.
+func (pk *Packet) Bytes() []byte {
+	p := make([]byte, 1+len(pk.Data))
+	p[0] = Header(pk.Port, pk.Channel)
+	copy(p[1:], pk.Data)
+	return p
+}
+
+func (pk *Packet) String() string {
+	return fmt.Sprintf("%s/%d: %v", pk.Port, pk.Channel, pk.Data)
+}
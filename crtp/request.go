@@ -0,0 +1,42 @@
+package crtp
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	DefaultRequestTimeout = 200 * time.Millisecond
+	DefaultRequestRetries = 10
+)
+
+var ErrNoReply = fmt.Errorf("No reply from Crazyflie")
+
+// Request sends req and waits for a reply on the same port and channel
+// for which match returns true. The request is resent up to retries times
+// if there's no matching reply within timeout.
+func (c *Conn) Request(req *Packet, match func(*Packet) bool, timeout time.Duration, retries int) (*Packet, error) {
+	sub := c.Subscribe(req.Port, req.Channel)
+	defer c.Unsubscribe(sub)
+	for try := 0; try <= retries; try++ {
+		if err := c.Send(req); err != nil {
+			return nil, err
+		}
+		deadline := time.After(timeout)
+	wait:
+		for {
+			select {
+			case pk, ok := <-sub:
+				if !ok {
+					return nil, ErrClosed
+				}
+				if match(pk) {
+					return pk, nil
+				}
+			case <-deadline:
+				break wait
+			}
+		}
+	}
+	return nil, ErrNoReply
+}
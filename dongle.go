@@ -0,0 +1,211 @@
+package cflie
+
+import (
+	"context"
+	"fmt"
+)
+
+var emptyPacket = []byte{0xFF}
+
+// How many times to ping Crazyflie before reporting it as unreachable.
+const openEndpointPings = 10
+
+var ErrNoAck = fmt.Errorf("Crazyflie does not respond")
+var ErrDongleLost = fmt.Errorf("CrazyRadio dongle is lost")
+
+// dongle processes orders assigned to a single CrazyRadio dongle.
+// It owns the device from the moment it's started and closes it on exit.
+type dongle struct {
+	key        string
+	dev        Device
+	ordersChan chan Order
+	// The dongle state is sent to readyChan after each order and each time an endpoint is closed
+	readyChan chan<- dongleState
+	// Failed orders which could be processed by other dongles are sent to retryChan
+	retryChan chan<- Order
+	errChan   chan<- error
+	// Closed when the station is closed
+	quit <-chan bool
+	// Closed by the scheduler when the dongle is unplugged
+	lost   chan bool
+	events *eventHub
+	opts   Options
+	log    Logger
+
+	// Endpoints served by the dongle in turns
+	links []*link
+	// Index of the link to serve next
+	next int
+	// The link the radio is tuned to, if any
+	tuned *link
+	buf   []byte
+}
+
+// dongleState is reported by a dongle to the scheduler.
+type dongleState struct {
+	key string
+	// Set if the dongle has completed an order and is ready for the next one
+	ready     bool
+	endpoints int
+}
+
+func (d *dongle) run() {
+	d.log.Debug("Dongle goroutine started", "dongle", d.key)
+	defer func() {
+		if err := d.dev.Close(); err != nil {
+			d.errChan <- err
+		}
+	}()
+	for {
+		var order Order
+		var ok bool
+		if len(d.links) == 0 {
+			order, ok = <-d.ordersChan
+		} else {
+			order, ok = d.serve()
+		}
+		if !ok {
+			// The scheduler closes ordersChan once the station is closed or the dongle is lost
+			err := d.stopErr()
+			if err == nil {
+				err = ErrStationClosed
+			}
+			for len(d.links) > 0 {
+				d.closeLink(d.links[0], err)
+			}
+			return
+		}
+		d.log.Debug("Got an order", "dongle", d.key, "order", order)
+		if err := d.stopErr(); err != nil {
+			d.fail(order, err)
+			continue
+		}
+		// Orders retune the radio
+		d.tuned = nil
+		d.process(order)
+		d.report(true)
+	}
+}
+
+func (d *dongle) report(ready bool) {
+	select {
+	case d.readyChan <- dongleState{key: d.key, ready: ready, endpoints: len(d.links)}:
+	case <-d.quit:
+	case <-d.lost:
+	}
+}
+
+// stopErr returns ErrStationClosed or ErrDongleLost if the dongle must stop working.
+func (d *dongle) stopErr() error {
+	select {
+	case <-d.quit:
+		return ErrStationClosed
+	case <-d.lost:
+		return ErrDongleLost
+	default:
+	}
+	return nil
+}
+
+// fail reports that the dongle could not process the order.
+// Scan chunks are given to other dongles, if possible.
+func (d *dongle) fail(order Order, err error) {
+	cur, ok := order.(*scanChunkOrder)
+	if !ok {
+		order.Fail(err)
+		d.events.emit(orderEvent(OrderFailed, order, d.key, err))
+		return
+	}
+	cur.failed[d.key] = true
+	cur.lastErr = err
+	if len(cur.failed) >= maxScanChunkAttempts || cur.ctx.Err() != nil {
+		cur.Fail(err)
+		d.events.emit(orderEvent(OrderFailed, order, d.key, err))
+		return
+	}
+	d.log.Warn("Scan chunk failed, retrying on another dongle", "dongle", d.key, "order", cur, "err", err)
+	select {
+	case d.retryChan <- cur:
+	case <-d.quit:
+		cur.Fail(ErrStationClosed)
+	}
+}
+
+func (d *dongle) process(order Order) {
+	// The order might have been cancelled while waiting in the dongle queue
+	if err := orderErr(order); err != nil {
+		order.Fail(err)
+		d.events.emit(orderEvent(OrderTimedOut, order, d.key, err))
+		return
+	}
+	switch order.(type) {
+	case *scanChunkOrder:
+		cur := order.(*scanChunkOrder)
+		addr, err := scanChunk(d.dev, cur)
+		if err != nil {
+			d.fail(cur, err)
+			return
+		}
+		d.log.Debug("Scan chunk done", "dongle", d.key, "order", cur, "found", addr)
+		cur.respCh <- &scanChunkResp{addr: addr}
+	case *openEndpointOrder:
+		cur := order.(*openEndpointOrder)
+		d.openEndpoint(cur)
+	default:
+		order.Fail(fmt.Errorf("Unknown order type: %T", order))
+	}
+
+}
+
+func scanChunk(dev Device, order *scanChunkOrder) (addr []string, err error) {
+	if err = dev.SetRadioAddress(order.radioAddr); err != nil {
+		return
+	}
+	if addr, err = dev.ScanChunk(order.rate, order.fromCh, order.toCh); err != nil {
+		return
+	}
+	return withRadioAddr(addr, order.radioAddr)
+}
+
+// withRadioAddr adds the radio address to URIs reported by Device.ScanChunk.
+func withRadioAddr(list []string, radioAddr [5]byte) (res []string, err error) {
+	for _, uri := range list {
+		rate, ch, err := ParseAddr(uri)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, RadioURI(rate, ch, radioAddr))
+	}
+	return
+}
+
+// ackStatus decodes the status byte which precedes the ACK payload:
+// bit 0 is set when the packet has been acknowledged,
+// bits 4-7 hold the number of retransmissions.
+func ackStatus(b byte) (acked bool, retries int) {
+	return b&1 != 0, int(b >> 4)
+}
+
+// ping checks that Crazyflie acknowledges packets on the current rate and channel.
+func ping(ctx context.Context, dev Device) (err error) {
+	buf := make([]byte, 64)
+	for try := 0; try < openEndpointPings; try++ {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if _, err = dev.Write(emptyPacket); err != nil {
+			continue
+		}
+		var n int
+		if n, err = dev.Read(buf); err != nil {
+			continue
+		}
+		if acked, _ := ackStatus(buf[0]); n > 0 && acked {
+			return nil
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("%v: %v", ErrNoAck, err)
+	}
+	return ErrNoAck
+}
@@ -0,0 +1,90 @@
+package cflie
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// EndpointLostTimeout is the default of Options.EndpointLostTimeout.
+const EndpointLostTimeout = 2 * time.Second
+
+var ErrEndpointClosed = fmt.Errorf("Endpoint is closed")
+var ErrLinkLost = fmt.Errorf("Link to Crazyflie is lost")
+
+// Endpoint is a link to a Crazyflie.
+//
+// Packets sent to SendChan are delivered to Crazyflie; non-empty packets received
+// from it are queued in RecvChan, see OpenOptions. RecvChan is closed once
+// the link is down, see Done and Err.
+type Endpoint struct {
+	RecvChan <-chan []byte
+	SendChan chan<- []byte
+
+	quit      chan bool
+	closeOnce sync.Once
+	done      chan bool
+	mu        sync.Mutex
+	err       error
+	poll      PollPolicy
+	// Poll policies must not let the link be lost, see PollPolicy.MaxInterval
+	lostTimeout time.Duration
+	overflow    OverflowPolicy
+	weight      int
+	stats       linkStats
+}
+
+func newEndpoint(recvChan <-chan []byte, sendChan chan<- []byte, opts Options, overflow OverflowPolicy) *Endpoint {
+	return &Endpoint{
+		RecvChan:    recvChan,
+		SendChan:    sendChan,
+		quit:        make(chan bool),
+		done:        make(chan bool),
+		poll:        opts.PollPolicy,
+		lostTimeout: opts.EndpointLostTimeout,
+		overflow:    overflow,
+		weight:      1,
+	}
+}
+
+// Close closes the link and releases the dongle. It waits until the link is down.
+// Closing SendChan has the same effect, but does not wait.
+// For an Endpoint which is not created by a Station, Close just closes SendChan.
+func (ep *Endpoint) Close() error {
+	err := ErrEndpointClosed
+	ep.closeOnce.Do(func() {
+		if ep.quit == nil {
+			close(ep.SendChan)
+		} else {
+			close(ep.quit)
+		}
+		err = nil
+	})
+	if ep.done != nil {
+		<-ep.done
+	}
+	return err
+}
+
+// Done returns a channel which is closed once the link is down.
+func (ep *Endpoint) Done() <-chan bool {
+	return ep.done
+}
+
+// Err returns nil while the link is up. After Done is closed, Err returns
+// the reason: ErrEndpointClosed if the endpoint was closed by the user,
+// ErrStationClosed if the station was closed, ErrDongleLost if the dongle
+// was unplugged, or the link failure.
+func (ep *Endpoint) Err() error {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+	return ep.err
+}
+
+// finish is called by the dongle goroutine once the link is down.
+func (ep *Endpoint) finish(err error) {
+	ep.mu.Lock()
+	ep.err = err
+	ep.mu.Unlock()
+	close(ep.done)
+}
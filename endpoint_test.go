@@ -0,0 +1,249 @@
+package cflie
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func waitDone(t *testing.T, ep *Endpoint) {
+	t.Helper()
+	select {
+	case <-ep.Done():
+	case <-time.After(EndpointLostTimeout + time.Second):
+		t.Fatalf("Endpoint is not done")
+	}
+	if _, ok := <-ep.RecvChan; ok {
+		t.Errorf("RecvChan must be closed once the endpoint is done")
+	}
+}
+
+func TestEndpointClose(t *testing.T) {
+	dev := &testDevice{}
+	st := startTestStation(t, dev)
+	ep := openTestEndpoint(t, st, OpenOptions{})
+	if err := ep.Err(); err != nil {
+		t.Errorf("Err must be nil while the link is up, got %v", err)
+	}
+	if err := ep.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	waitDone(t, ep)
+	if err := ep.Err(); err != ErrEndpointClosed {
+		t.Errorf("Err: want ErrEndpointClosed, got %v", err)
+	}
+
+	// The dongle must be released, so it's possible to open another endpoint
+	ep = openTestEndpoint(t, st, OpenOptions{})
+
+	// USB failures tear the link down
+	dev.setLink(false, fmt.Errorf("unplugged"))
+	waitDone(t, ep)
+	if ep.Err() == nil || ep.Err() == ErrEndpointClosed {
+		t.Errorf("Err: want USB error, got %v", ep.Err())
+	}
+}
+
+func TestEndpointLost(t *testing.T) {
+	dev := &testDevice{}
+	st := startTestStation(t, dev)
+	ep := openTestEndpoint(t, st, OpenOptions{})
+	dev.setLink(true, nil)
+	waitDone(t, ep)
+	if err := ep.Err(); err != ErrLinkLost {
+		t.Errorf("Err: want ErrLinkLost, got %v", err)
+	}
+
+	if _, err := st.Open("radio://0/10/250K"); err == nil {
+		t.Errorf("Open must fail when Crazyflie does not respond")
+	}
+}
+
+func TestEndpointDongleLost(t *testing.T) {
+	dev := &testDevice{}
+	hub := &testHub{info: &testDeviceInfo{dev: dev}, unplug: make(chan bool)}
+	st, err := Start(hub)
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer st.Close()
+
+	ep, err := st.Open("radio://0/10/250K")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	close(hub.unplug)
+	waitDone(t, ep)
+	if err = ep.Err(); err != ErrDongleLost {
+		t.Errorf("Err: want ErrDongleLost, got %v", err)
+	}
+	waitFor(t, "the lost dongle to be closed", dev.isClosed)
+}
+
+func TestLinkStats(t *testing.T) {
+	var ls linkStats
+	ls.record(true, 0x01, nil)
+	ls.record(false, 0x31, []byte{0x30, 1, 2})
+	ls.record(true, 0x01, []byte{0xF7, 0x01, 42})
+	ls.record(true, 0xA0, nil)
+	s := ls.snapshot()
+	want := LinkStats{Time: s.Time, Sent: 4, Acked: 3, Retries: 13, Pings: 3, Payloads: 2, Quality: 75, RSSI: -42}
+	if s != want {
+		t.Errorf("Unexpected stats. Want: %+v, got: %+v", want, s)
+	}
+
+	for i := 0; i < linkQualityWindow; i++ {
+		ls.record(true, 0x01, nil)
+	}
+	if q := ls.snapshot().Quality; q != 100 {
+		t.Errorf("Quality must only account for the last %d packets, got %v", linkQualityWindow, q)
+	}
+}
+
+func TestEndpointStats(t *testing.T) {
+	ep := openTestEndpoint(t, startTestStation(t, nil), OpenOptions{})
+	c := ep.WatchStats(10 * time.Millisecond)
+	s, ok := <-c
+	if !ok {
+		t.Fatalf("Stats channel is closed while the link is up")
+	}
+	if s.Sent == 0 || s.Acked != s.Sent || s.Pings != s.Sent || s.Quality != 100 {
+		t.Errorf("Unexpected stats of an idle link: %+v", s)
+	}
+	ep.Close()
+	for range c {
+	}
+}
+
+func TestPollPolicy(t *testing.T) {
+	p := PollPolicy{MinInterval: 0, MaxInterval: 4 * time.Millisecond, Backoff: 2}
+	var got []time.Duration
+	cur := time.Duration(0)
+	for i := 0; i < 5; i++ {
+		cur = p.next(cur, false)
+		got = append(got, cur)
+	}
+	want := []time.Duration{minPollBackoff, 2 * minPollBackoff, 4 * minPollBackoff, 4 * time.Millisecond, 4 * time.Millisecond}
+	if fmt.Sprint(want) != fmt.Sprint(got) {
+		t.Errorf("Unexpected backoff. Want: %v, got: %v", want, got)
+	}
+	if cur = p.next(cur, true); cur != 0 {
+		t.Errorf("Payload must reset the interval to MinInterval, got %v", cur)
+	}
+
+	for _, bad := range []PollPolicy{
+		{MinInterval: -1, MaxInterval: time.Millisecond, Backoff: 2},
+		{MinInterval: 2 * time.Millisecond, MaxInterval: time.Millisecond, Backoff: 2},
+		{MaxInterval: EndpointLostTimeout, Backoff: 2},
+		{MaxInterval: time.Millisecond, Backoff: 0.5},
+	} {
+		if bad.Validate() == nil {
+			t.Errorf("Validate must fail for %+v", bad)
+		}
+	}
+}
+
+func TestEndpointPollBackoff(t *testing.T) {
+	const maxInterval = 20 * time.Millisecond
+	ep := openTestEndpoint(t, startTestStation(t, nil), OpenOptions{})
+	if err := ep.SetPollPolicy(PollPolicy{MaxInterval: maxInterval, Backoff: 2}); err != nil {
+		t.Fatalf("SetPollPolicy: %v", err)
+	}
+	pingsAfter := func(before, n uint64) func() bool {
+		return func() bool { return ep.Stats().Pings >= before+n }
+	}
+	// Crazyflie only returns empty ACKs, so the interval reaches maxInterval within 10 pings
+	waitFor(t, "the polling interval to grow", pingsAfter(ep.Stats().Pings, 10))
+	start, before := time.Now(), ep.Stats().Pings
+	waitFor(t, "5 pings", pingsAfter(before, 5))
+	// Polling slower than expected is fine, it's not a timing test
+	if elapsed := time.Now().Sub(start); elapsed < 4*maxInterval {
+		t.Errorf("Idle link must back off to %v, but it sent 5 pings in %v", maxInterval, elapsed)
+	}
+}
+
+func TestEndpointOverflow(t *testing.T) {
+	const queueSize = 16
+	for _, policy := range []OverflowPolicy{DropOldest, DropNewest, Block} {
+		t.Run(policy.String(), func(t *testing.T) {
+			dev := &testDevice{}
+			ep := openTestEndpoint(t, startTestStation(t, dev), OpenOptions{RecvQueueSize: queueSize, Overflow: policy})
+			dev.mu.Lock()
+			dev.payloads = true
+			dev.mu.Unlock()
+
+			waitFor(t, "the queue to overflow", func() bool {
+				if policy == Block {
+					return len(ep.RecvChan) == queueSize
+				}
+				return ep.Stats().Dropped > 0
+			})
+			// With DropOldest, the dongle might be replacing a packet right now
+			if n := len(ep.RecvChan); n != queueSize && !(policy == DropOldest && n == queueSize-1) {
+				t.Fatalf("%v: want %d queued packets, got %d", policy, queueSize, n)
+			}
+			dropped := ep.Stats().Dropped
+			if policy == Block && dropped != 0 {
+				t.Errorf("%v: %d packets dropped", policy, dropped)
+			}
+			if policy != Block && dropped == 0 {
+				t.Errorf("%v: no packets dropped", policy)
+			}
+
+			// With DropOldest, the dongle keeps replacing queued packets while they're read
+			first := <-ep.RecvChan
+			prev := first[0]
+			for i := 1; i < queueSize; i++ {
+				p := <-ep.RecvChan
+				if policy != DropOldest && p[0] != prev+1 {
+					t.Errorf("%v: packet #%d follows #%d", policy, p[0], prev)
+				}
+				prev = p[0]
+			}
+			if policy != DropOldest && first[0] != 0 {
+				t.Errorf("%v: the first packet is #%d, want #0", policy, first[0])
+			}
+		})
+	}
+}
+
+func TestSharedDongle(t *testing.T) {
+	st := startTestStation(t, nil)
+
+	var eps []*Endpoint
+	for i := 0; i < MaxEndpointsPerDongle; i++ {
+		ep, err := st.Open(fmt.Sprintf("radio://0/%d/2M", 10+i))
+		if err != nil {
+			t.Fatalf("Open #%d: %v", i, err)
+		}
+		defer ep.Close()
+		eps = append(eps, ep)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := st.OpenContext(ctx, "radio://0/99/2M", OpenOptions{}); err == nil {
+		t.Errorf("Open must fail once MaxEndpointsPerDongle endpoints share the dongle")
+	}
+
+	if err := eps[0].Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	for i, ep := range eps[1:] {
+		before := ep.Stats().Sent
+		waitFor(t, fmt.Sprintf("endpoint #%d to be served", i+1), func() bool { return ep.Stats().Sent > before })
+		if err := ep.Err(); err != nil {
+			t.Errorf("Endpoint #%d is down: %v", i+1, err)
+		}
+	}
+
+	// A slot is free again
+	ep, err := st.Open("radio://0/99/2M")
+	if err != nil {
+		t.Fatalf("Open after Close: %v", err)
+	}
+	ep.Close()
+	if err := eps[1].SetWeight(0); err == nil {
+		t.Errorf("SetWeight(0) must fail")
+	}
+}
@@ -0,0 +1,155 @@
+package cflie
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// EventType tells what has happened in a Station.
+type EventType int
+
+const (
+	// A dongle is plugged in and opened
+	DongleAdded EventType = iota
+	// A dongle is unplugged
+	DongleLost
+	// A dongle could not be opened. It's blacklisted for BlackListDuration.
+	DongleFailed
+	// An order is given to a dongle
+	OrderAssigned
+	// An order is timed out or cancelled before it's completed
+	OrderTimedOut
+	// An order has failed
+	OrderFailed
+	// A link to Crazyflie is up
+	EndpointOpened
+	// A link to Crazyflie is down, see Event.Err
+	EndpointClosed
+	// The dongle tracker or the scheduler has reported an error
+	StationError
+	// A Crazyflie has appeared, see Station.Watch
+	CrazyflieFound
+	// A Crazyflie has disappeared, see Station.Watch
+	CrazyflieLost
+)
+
+func (t EventType) String() string {
+	switch t {
+	case DongleAdded:
+		return "DongleAdded"
+	case DongleLost:
+		return "DongleLost"
+	case DongleFailed:
+		return "DongleFailed"
+	case OrderAssigned:
+		return "OrderAssigned"
+	case OrderTimedOut:
+		return "OrderTimedOut"
+	case OrderFailed:
+		return "OrderFailed"
+	case EndpointOpened:
+		return "EndpointOpened"
+	case EndpointClosed:
+		return "EndpointClosed"
+	case StationError:
+		return "StationError"
+	case CrazyflieFound:
+		return "CrazyflieFound"
+	case CrazyflieLost:
+		return "CrazyflieLost"
+	}
+	return fmt.Sprintf("EventType:#%d", int(t))
+}
+
+// Event is emitted by a Station, see Station.Subscribe.
+type Event struct {
+	Type EventType
+	Time time.Time
+	// Dongle key (DeviceInfo.String()), if the event is related to a dongle
+	Dongle string
+	// Order description, like "open radio://0/10/250K", for order events
+	Order string
+	// Crazyflie address, for endpoint and Crazyflie events
+	Addr string
+	Err  error
+}
+
+func (e Event) String() string {
+	s := e.Type.String()
+	if e.Dongle != "" {
+		s += " dongle=" + e.Dongle
+	}
+	if e.Order != "" {
+		s += fmt.Sprintf(" order=%q", e.Order)
+	}
+	if e.Addr != "" {
+		s += " addr=" + e.Addr
+	}
+	if e.Err != nil {
+		s += fmt.Sprintf(" err=%q", e.Err)
+	}
+	return s
+}
+
+// Size of a subscriber queue. If a subscriber does not keep up, extra events are dropped.
+const eventQueueSize = 64
+
+// eventHub delivers events to subscribers.
+type eventHub struct {
+	mu     sync.Mutex
+	subs   []chan Event
+	closed bool
+}
+
+func (h *eventHub) subscribe() <-chan Event {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	sub := make(chan Event, eventQueueSize)
+	if h.closed {
+		close(sub)
+		return sub
+	}
+	h.subs = append(h.subs, sub)
+	return sub
+}
+
+func (h *eventHub) unsubscribe(c <-chan Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i, sub := range h.subs {
+		if sub == c {
+			h.subs = append(h.subs[:i], h.subs[i+1:]...)
+			close(sub)
+			return
+		}
+	}
+}
+
+func (h *eventHub) emit(e Event) {
+	e.Time = time.Now()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, sub := range h.subs {
+		select {
+		case sub <- e:
+		default:
+		}
+	}
+}
+
+// close closes all the subscriber channels.
+func (h *eventHub) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, sub := range h.subs {
+		close(sub)
+	}
+	h.subs = nil
+	h.closed = true
+}
+
+// orderEvent returns an event about the order.
+func orderEvent(t EventType, order Order, dongleKey string, err error) Event {
+	return Event{Type: t, Dongle: dongleKey, Order: fmt.Sprint(order), Err: err}
+}
@@ -0,0 +1,266 @@
+package cflie
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// MaxEndpointsPerDongle is the default of Options.MaxEndpointsPerDongle.
+// Endpoints sharing a dongle are served in turns, switching the radio
+// between their rates, channels and addresses; see Endpoint.SetWeight.
+// New endpoints are opened on the least loaded dongle.
+const MaxEndpointsPerDongle = 4
+
+// link is an endpoint served by a dongle.
+type link struct {
+	ep        *Endpoint
+	rate      DataRate
+	ch        uint8
+	radioAddr [5]byte
+	recvChan  chan []byte
+	sendChan  <-chan []byte
+	lastAck   time.Time
+	usbErrors int
+	// Current polling interval, see PollPolicy
+	interval time.Duration
+	nextPoll time.Time
+}
+
+func (l *link) String() string {
+	return RadioURI(l.rate, l.ch, l.radioAddr)
+}
+
+// SetWeight sets how many packets in a row the endpoint may exchange with Crazyflie
+// before the dongle is given to the next endpoint sharing it. The default is 1.
+func (ep *Endpoint) SetWeight(weight int) error {
+	if weight < 1 {
+		return fmt.Errorf("Weight must be at least 1, got %d", weight)
+	}
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+	ep.weight = weight
+	return nil
+}
+
+func (ep *Endpoint) getWeight() int {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+	return ep.weight
+}
+
+func (d *dongle) openEndpoint(order *openEndpointOrder) {
+	l := &link{
+		rate:      order.rate,
+		ch:        order.ch,
+		radioAddr: order.radioAddr,
+	}
+	err := d.tune(l)
+	if err == nil {
+		err = ping(order.ctx, d.dev)
+	}
+	if err != nil {
+		d.fail(order, err)
+		return
+	}
+	l.recvChan = make(chan []byte, order.queueSize)
+	sendChan := make(chan []byte)
+	l.sendChan = sendChan
+	l.ep = newEndpoint(l.recvChan, sendChan, d.opts, order.overflow)
+	l.lastAck = time.Now()
+	d.links = append(d.links, l)
+	d.events.emit(Event{Type: EndpointOpened, Dongle: d.key, Addr: l.String()})
+	order.respChan <- &openEndpointResp{ep: l.ep}
+}
+
+// closeLink tears the link down and reports the new number of endpoints to the scheduler.
+func (d *dongle) closeLink(l *link, err error) {
+	for i, cur := range d.links {
+		if cur == l {
+			d.links = append(d.links[:i], d.links[i+1:]...)
+			break
+		}
+	}
+	if d.tuned == l {
+		d.tuned = nil
+	}
+	if err != ErrEndpointClosed && err != ErrStationClosed {
+		// Including ErrDongleLost
+		d.log.Warn("Endpoint is down", "dongle", d.key, "addr", l, "err", err)
+	}
+	// The event goes before Endpoint.Close returns
+	d.events.emit(Event{Type: EndpointClosed, Dongle: d.key, Addr: l.String(), Err: err})
+	// Err must be set by the time RecvChan readers notice it's closed
+	l.ep.finish(err)
+	close(l.recvChan)
+	d.report(false)
+}
+
+// tune switches the radio to the link rate, channel and address.
+func (d *dongle) tune(l *link) error {
+	if d.tuned == l {
+		return nil
+	}
+	d.tuned = nil
+	if err := d.dev.SetRadioAddress(l.radioAddr); err != nil {
+		return err
+	}
+	if err := d.dev.SetRateAndChannel(l.rate, l.ch); err != nil {
+		return err
+	}
+	d.tuned = l
+	return nil
+}
+
+// serve exchanges packets with Crazyflies over the links in turns
+// until there's a new order. ok is false if ordersChan is closed.
+func (d *dongle) serve() (order Order, ok bool) {
+	for {
+		if len(d.links) == 0 {
+			order, ok = <-d.ordersChan
+			return
+		}
+		select {
+		case order, ok = <-d.ordersChan:
+			return
+		default:
+		}
+		busy := false
+		// One round
+		for n := len(d.links); n > 0 && len(d.links) > 0; n-- {
+			d.next %= len(d.links)
+			l := d.links[d.next]
+			served, err := d.turn(l)
+			if err != nil {
+				// The next link takes its index
+				d.closeLink(l, err)
+				busy = true
+				continue
+			}
+			busy = busy || served
+			d.next++
+		}
+		if !busy {
+			if order, ok, got := d.wait(); got {
+				return order, ok
+			}
+		}
+	}
+}
+
+// turn exchanges up to the endpoint weight packets with Crazyflie.
+// It reports whether the link had anything to send or poll.
+func (d *dongle) turn(l *link) (served bool, err error) {
+	for i := l.ep.getWeight(); i > 0; i-- {
+		var p []byte
+		var ok bool
+		select {
+		case <-l.ep.quit:
+			return served, ErrEndpointClosed
+		case p, ok = <-l.sendChan:
+			if !ok {
+				return served, ErrEndpointClosed
+			}
+		default:
+		}
+		ping := !ok
+		if ping {
+			if time.Now().Before(l.nextPoll) {
+				return
+			}
+			p = emptyPacket
+		}
+		served = true
+		if err = d.exchange(l, p, ping); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// wait blocks until there's an order, a packet to send or a link to poll.
+// got is set if there is an order.
+func (d *dongle) wait() (order Order, ok bool, got bool) {
+	// The number of links varies, so reflect.Select is used
+	cases := []reflect.SelectCase{{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(d.ordersChan)}}
+	nextPoll := d.links[0].nextPoll
+	for _, l := range d.links {
+		if l.nextPoll.Before(nextPoll) {
+			nextPoll = l.nextPoll
+		}
+		cases = append(cases,
+			reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(l.ep.quit)},
+			reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(l.sendChan)})
+	}
+	timer := time.NewTimer(nextPoll.Sub(time.Now()))
+	defer timer.Stop()
+	cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(timer.C)})
+
+	chosen, v, recvOK := reflect.Select(cases)
+	switch {
+	case chosen == 0:
+		if !recvOK {
+			return nil, false, true
+		}
+		return v.Interface().(Order), true, true
+	case chosen == len(cases)-1:
+		// Time to poll
+		return
+	}
+	l := d.links[(chosen-1)/2]
+	if (chosen-1)%2 == 0 || !recvOK {
+		// ep.quit or SendChan is closed
+		d.closeLink(l, ErrEndpointClosed)
+		return
+	}
+	if err := d.exchange(l, v.Interface().([]byte), false); err != nil {
+		d.closeLink(l, err)
+	}
+	return
+}
+
+// exchange sends a packet to Crazyflie and delivers the ACK payload, if any.
+// It returns an error if the link must be torn down.
+func (d *dongle) exchange(l *link, p []byte, ping bool) error {
+	if err := d.tune(l); err != nil {
+		return d.usbError(l, fmt.Errorf("Unable to tune the radio: %v", err))
+	}
+	if _, err := d.dev.Write(p); err != nil {
+		return d.usbError(l, fmt.Errorf("Unable to write to device: %v", err))
+	}
+	n, err := d.dev.Read(d.buf)
+	if err != nil {
+		return d.usbError(l, fmt.Errorf("Unable to read from device: %v", err))
+	}
+	l.usbErrors = 0
+	var status byte
+	p = make([]byte, 0, n)
+	if n > 0 {
+		// Cut off the ACK byte
+		status = d.buf[0]
+		p = append(p, d.buf[1:n]...)
+	}
+	l.ep.stats.record(ping, status, p)
+	// Poll faster if Crazyflie has something to say, or if it's likely to reply
+	l.interval = l.ep.pollPolicy().next(l.interval, !ping || len(p) > 0)
+	l.nextPoll = time.Now().Add(l.interval)
+	if acked, _ := ackStatus(status); acked {
+		l.lastAck = time.Now()
+	} else if time.Now().Sub(l.lastAck) > d.opts.EndpointLostTimeout {
+		return ErrLinkLost
+	}
+
+	if len(p) > 0 {
+		return d.deliver(l.ep, l.recvChan, p)
+	}
+	return nil
+}
+
+// usbError returns err after Options.MaxUSBErrors consecutive USB failures of the link.
+func (d *dongle) usbError(l *link, err error) error {
+	l.usbErrors++
+	if l.usbErrors >= d.opts.MaxUSBErrors {
+		return err
+	}
+	return nil
+}
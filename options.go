@@ -0,0 +1,173 @@
+package cflie
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// Logger receives log messages of a Station. Messages are followed by
+// alternating keys and values, like in log/slog; *slog.Logger implements Logger.
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+// RadioConfig is the dongle radio setup which is the same for all the links.
+type RadioConfig struct {
+	// From 0 (-18dBm) to 3 (0dBm)
+	Power uint8
+	// Auto retransmit count, 0..15
+	ARC uint8
+	// Auto retransmit delay. If bit 7 is set, the delay is long enough
+	// for an ACK payload of that many bytes; otherwise, it's (ARD+1)*250us.
+	ARD uint8
+}
+
+// DefaultRadioConfig is applied to dongles when they are opened.
+var DefaultRadioConfig = RadioConfig{Power: 3, ARC: 10, ARD: 0x80 | 32}
+
+const (
+	maxRadioPower = 3
+	maxARC        = 15
+)
+
+// Validate returns an error if the dongle would reject the config.
+func (c RadioConfig) Validate() error {
+	if c.Power > maxRadioPower {
+		return fmt.Errorf("Invalid radio power: %d, must be 0..%d", c.Power, maxRadioPower)
+	}
+	if c.ARC > maxARC {
+		return fmt.Errorf("Invalid ARC: %d, must be 0..%d", c.ARC, maxARC)
+	}
+	return nil
+}
+
+// RadioConfigurer is implemented by devices which support RadioConfig.
+type RadioConfigurer interface {
+	SetRadioConfig(cfg RadioConfig) error
+}
+
+// IntervalHub is implemented by hubs which poll the list of dongles
+// with a configurable interval.
+type IntervalHub interface {
+	Hub
+	ListPushEvery(interval time.Duration, cancelChan <-chan bool, errChan chan<- error) <-chan []DeviceInfo
+}
+
+// Options configure a Station. Zero fields mean the defaults.
+type Options struct {
+	// If nil, slog.Default() is used
+	Logger Logger
+	// How long a dongle which could not be opened is ignored.
+	// The default is BlackListDuration.
+	BlackListDuration time.Duration
+	// Scan timeout per rate and radio address, used by Scan, and by ScanContext
+	// if its context has no deadline. The default is 10s.
+	ScanChunkTimeout time.Duration
+	// Open timeout. The default is 5s.
+	OpenTimeout time.Duration
+	// How often the scheduler checks for timed out orders. The default is 1s.
+	SchedulerTick time.Duration
+	// How often the hub lists dongles, if it's an IntervalHub. The default is up to the hub.
+	ListInterval time.Duration
+	// If set, the config is applied to dongles which implement RadioConfigurer,
+	// instead of their defaults.
+	Radio *RadioConfig
+	// Number of channels scanned by a dongle at once. Smaller chunks spread
+	// a scan across more idle dongles, at most MaxChannel. The default is ScanChunkChannels.
+	ScanChunkChannels int
+	// How many endpoints may share a dongle. The default is MaxEndpointsPerDongle.
+	MaxEndpointsPerDongle int
+	// An endpoint is torn down if Crazyflie has not acknowledged a single packet
+	// for this long. The default is EndpointLostTimeout.
+	EndpointLostTimeout time.Duration
+	// An endpoint is torn down after this many consecutive USB transfer failures.
+	// The default is 10.
+	MaxUSBErrors int
+	// Polling policy of newly opened endpoints, see Endpoint.SetPollPolicy.
+	// The default is DefaultPollPolicy.
+	PollPolicy PollPolicy
+}
+
+const (
+	defaultScanChunkTimeout = 10 * time.Second
+	defaultOpenTimeout      = 5 * time.Second
+	defaultSchedulerTick    = time.Second
+	defaultMaxUSBErrors     = 10
+)
+
+// withDefaults returns the options with zero fields set to the defaults.
+func (o Options) withDefaults() Options {
+	if o.Logger == nil {
+		o.Logger = slog.Default()
+	}
+	if o.BlackListDuration <= 0 {
+		o.BlackListDuration = BlackListDuration
+	}
+	if o.ScanChunkTimeout <= 0 {
+		o.ScanChunkTimeout = defaultScanChunkTimeout
+	}
+	if o.OpenTimeout <= 0 {
+		o.OpenTimeout = defaultOpenTimeout
+	}
+	if o.SchedulerTick <= 0 {
+		o.SchedulerTick = defaultSchedulerTick
+	}
+	if o.ScanChunkChannels <= 0 {
+		o.ScanChunkChannels = ScanChunkChannels
+	}
+	if o.MaxEndpointsPerDongle <= 0 {
+		o.MaxEndpointsPerDongle = MaxEndpointsPerDongle
+	}
+	if o.EndpointLostTimeout <= 0 {
+		o.EndpointLostTimeout = EndpointLostTimeout
+	}
+	if o.MaxUSBErrors <= 0 {
+		o.MaxUSBErrors = defaultMaxUSBErrors
+	}
+	if o.PollPolicy == (PollPolicy{}) {
+		o.PollPolicy = DefaultPollPolicy
+	}
+	return o
+}
+
+// validate returns an error if the options can't be used.
+func (o Options) validate() error {
+	if o.Radio != nil {
+		if err := o.Radio.Validate(); err != nil {
+			return fmt.Errorf("Invalid radio config: %v", err)
+		}
+	}
+	if o.ScanChunkChannels > MaxChannel {
+		return fmt.Errorf("ScanChunkChannels must not exceed %d, got %d", MaxChannel, o.ScanChunkChannels)
+	}
+	if err := o.PollPolicy.validate(o.EndpointLostTimeout); err != nil {
+		return fmt.Errorf("Invalid poll policy: %v", err)
+	}
+	return nil
+}
+
+// ScanOptions configure Station.ScanContext. Zero fields mean the defaults.
+type ScanOptions struct {
+	// Radio addresses to scan. The default is DefaultRadioAddress.
+	RadioAddrs [][5]byte
+	// Dongles to scan with
+	Dongles DongleSelector
+	// If set, the scan chunks are only given to dongles which are not wanted
+	// by other orders. Opening endpoints always takes precedence over queued scan chunks.
+	Background bool
+}
+
+// OpenOptions configure Station.OpenContext. Zero fields mean the defaults.
+type OpenOptions struct {
+	// Dongles to open the endpoint with
+	Dongles DongleSelector
+	// Capacity of Endpoint.RecvChan. The default is RecvQueueSize.
+	RecvQueueSize int
+	// What to do when RecvChan is full, see Endpoint.SetOverflowPolicy.
+	// The default is DefaultOverflowPolicy.
+	Overflow OverflowPolicy
+}
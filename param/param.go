@@ -0,0 +1,153 @@
+// Package param reads and writes Crazyflie firmware parameters over the radio.
+//
+// Parameters are addressed by "group.name", e.g. "pid_rate.roll_kp".
+// Reads are sent to channel 1 of the param port ([id] -> [id, value]),
+// writes to channel 2 ([id, value] -> [id, value]).
+package param
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/samofly/cflie/crtp"
+	"github.com/samofly/cflie/toc"
+)
+
+const (
+	ReadChannel  = 1
+	WriteChannel = 2
+
+	typeMask     = 0x0F
+	readOnlyFlag = 0x40
+)
+
+// Param type codes as reported in the TOC.
+var kinds = map[uint8]toc.Kind{
+	0x00: toc.Int8,
+	0x01: toc.Int16,
+	0x02: toc.Int32,
+	0x03: toc.Int64,
+	0x05: toc.Float16,
+	0x06: toc.Float32,
+	0x07: toc.Float64,
+	0x08: toc.Uint8,
+	0x09: toc.Uint16,
+	0x0A: toc.Uint32,
+	0x0B: toc.Uint64,
+}
+
+type Var struct {
+	toc.Element
+	Kind     toc.Kind
+	ReadOnly bool
+}
+
+func newVar(e toc.Element) *Var {
+	kind, ok := kinds[e.Type&typeMask]
+	if !ok {
+		kind = toc.Invalid
+	}
+	return &Var{Element: e, Kind: kind, ReadOnly: e.Type&readOnlyFlag != 0}
+}
+
+type Client struct {
+	conn *crtp.Conn
+	toc  *toc.TOC
+	vars map[string]*Var
+	// Serializes requests, since replies are matched by id only.
+	mu sync.Mutex
+}
+
+// New downloads the param TOC (or takes it from cache, if not nil) and returns a client.
+func New(conn *crtp.Conn, cache toc.Cache) (*Client, error) {
+	t, err := toc.Fetch(conn, crtp.PortParam, cache)
+	if err != nil {
+		return nil, err
+	}
+	c := &Client{conn: conn, toc: t, vars: make(map[string]*Var)}
+	for _, e := range t.Elements {
+		c.vars[e.FullName()] = newVar(e)
+	}
+	return c, nil
+}
+
+// CRC returns the firmware CRC of the param TOC.
+func (c *Client) CRC() uint32 {
+	return c.toc.CRC
+}
+
+// Vars returns all parameters sorted by name.
+func (c *Client) Vars() []*Var {
+	var list []*Var
+	for _, v := range c.vars {
+		list = append(list, v)
+	}
+	sort.Sort(byName(list))
+	return list
+}
+
+// Lookup finds a parameter by "group.name".
+func (c *Client) Lookup(name string) (*Var, error) {
+	v, ok := c.vars[name]
+	if !ok {
+		return nil, fmt.Errorf("Unknown param: %s", name)
+	}
+	if v.Kind == toc.Invalid {
+		return nil, fmt.Errorf("Param %s has unsupported type: 0x%02X", name, v.Type)
+	}
+	return v, nil
+}
+
+// Get reads the current value of a parameter. The value has a Go type matching
+// the param type (int8, uint16, float32, ...).
+func (c *Client) Get(name string) (interface{}, error) {
+	v, err := c.Lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	reply, err := c.conn.Request(crtp.NewPacket(crtp.PortParam, ReadChannel, []byte{v.ID}),
+		matchID(v.ID, v.Kind), crtp.DefaultRequestTimeout, crtp.DefaultRequestRetries)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read %s: %v", name, err)
+	}
+	return v.Kind.Decode(reply.Data[1:])
+}
+
+// Set writes a new value of a parameter. value can be any Go number,
+// which is converted to the param type, or a string to be parsed.
+func (c *Client) Set(name string, value interface{}) error {
+	v, err := c.Lookup(name)
+	if err != nil {
+		return err
+	}
+	if v.ReadOnly {
+		return fmt.Errorf("Param %s is read-only", name)
+	}
+	data, err := v.Kind.Encode(value)
+	if err != nil {
+		return fmt.Errorf("Invalid value for %s: %v", name, err)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, err = c.conn.Request(crtp.NewPacket(crtp.PortParam, WriteChannel, append([]byte{v.ID}, data...)),
+		matchID(v.ID, v.Kind), crtp.DefaultRequestTimeout, crtp.DefaultRequestRetries)
+	if err != nil {
+		return fmt.Errorf("Unable to write %s: %v", name, err)
+	}
+	return nil
+}
+
+func matchID(id uint8, kind toc.Kind) func(*crtp.Packet) bool {
+	return func(pk *crtp.Packet) bool {
+		return len(pk.Data) >= 1+kind.Size() && pk.Data[0] == id
+	}
+}
+
+type byName []*Var
+
+func (s byName) Len() int           { return len(s) }
+func (s byName) Less(i, j int) bool { return s[i].FullName() < s[j].FullName() }
+func (s byName) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
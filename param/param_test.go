@@ -0,0 +1,88 @@
+package param
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/samofly/cflie/crtp"
+	"github.com/samofly/cflie/crtp/crtptest"
+	"github.com/samofly/cflie/toc"
+)
+
+type testParam struct {
+	group, name string
+	typ         uint8
+	value       []byte
+}
+
+// fakeFlie serves param requests the same way as Crazyflie firmware does.
+func fakeFlie(params []*testParam) *crtptest.Flie {
+	var elems []toc.Element
+	for id, prm := range params {
+		elems = append(elems, toc.Element{ID: uint8(id), Group: prm.group, Name: prm.name, Type: prm.typ})
+	}
+	return &crtptest.Flie{
+		TOC: map[crtp.Port]*toc.TOC{crtp.PortParam: toc.New(0xDEADBEEF, elems, nil)},
+		Handle: func(pk *crtp.Packet) []*crtp.Packet {
+			var reply []byte
+			switch pk.Channel {
+			case ReadChannel:
+				reply = append([]byte{pk.Data[0]}, params[pk.Data[0]].value...)
+			case WriteChannel:
+				prm := params[pk.Data[0]]
+				prm.value = append([]byte(nil), pk.Data[1:]...)
+				reply = pk.Data
+			default:
+				return nil
+			}
+			return []*crtp.Packet{crtp.NewPacket(pk.Port, pk.Channel, reply)}
+		},
+	}
+}
+
+func TestGetSet(t *testing.T) {
+	kp := make([]byte, 4)
+	binary.LittleEndian.PutUint32(kp, math.Float32bits(250))
+	params := []*testParam{
+		{"pid_rate", "roll_kp", 0x06, kp},
+		{"imu_sensors", "HMC5883L", 0x08 | readOnlyFlag, []byte{1}},
+		{"flightctrl", "xmode", 0x08, []byte{0}},
+	}
+	conn := fakeFlie(params).Conn()
+	defer conn.Close()
+
+	cache := toc.NewMemCache()
+	c, err := New(conn, cache)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if c.CRC() != 0xDEADBEEF {
+		t.Errorf("Unexpected CRC: 0x%08X", c.CRC())
+	}
+	if _, ok := cache.Load(crtp.PortParam, 0xDEADBEEF); !ok {
+		t.Errorf("TOC is not cached")
+	}
+
+	v, err := c.Get("pid_rate.roll_kp")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if v != float32(250) {
+		t.Errorf("pid_rate.roll_kp: want float32(250), got %T(%v)", v, v)
+	}
+
+	if err = c.Set("flightctrl.xmode", "1"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if v, err = c.Get("flightctrl.xmode"); err != nil || v != uint8(1) {
+		t.Errorf("flightctrl.xmode: want uint8(1), got %T(%v), err: %v", v, v, err)
+	}
+
+	if err = c.Set("imu_sensors.HMC5883L", 0); err == nil {
+		t.Errorf("Set must fail for read-only params")
+	}
+	if _, err = c.Get("no.such"); err == nil {
+		t.Errorf("Get must fail for unknown params")
+	}
+}
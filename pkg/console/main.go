@@ -0,0 +1,37 @@
+// Streams the Crazyflie firmware console output to stdout.
+package console
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/samofly/cflie/console"
+	"github.com/samofly/cflie/crtp"
+	"github.com/samofly/cflie/pkg/flie"
+)
+
+var flags = flag.NewFlagSet("console", flag.ExitOnError)
+var target = flie.Flags(flags)
+
+func fail(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format, args...)
+	os.Exit(1)
+}
+
+func Main() {
+	flags.Parse(flag.Args()[1:])
+
+	ep, flieAddr, err := target.Connect()
+	if err != nil {
+		fail("%v\n", err)
+	}
+	fmt.Fprintf(os.Stderr, "Connected to %s\n", flieAddr)
+
+	r := console.NewReader(crtp.NewConn(ep))
+	if _, err = io.Copy(os.Stdout, r); err != nil {
+		fail("Unable to read console: %v\n", err)
+	}
+	fail("Link to %s is down: %v\n", flieAddr, ep.Err())
+}
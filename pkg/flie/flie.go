@@ -0,0 +1,125 @@
+// Package flie connects station-based commands to a Crazyflie.
+package flie
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/samofly/cflie"
+	"github.com/samofly/cflie/usb"
+)
+
+// CacheDir is the default location of downloaded param and log TOCs.
+var CacheDir = filepath.Join(os.Getenv("HOME"), ".cflie", "toc")
+
+// Target selects a Crazyflie to connect to.
+type Target struct {
+	// Explicit address, like radio://0/10/250K or radio://0/80/2M/E7E7E7E7E7.
+	// If empty, the spectrum is scanned.
+	Addr string
+	// Index of the Crazyflie in the scan results. If negative and more than one
+	// Crazyflie is found, the user is asked to choose.
+	Index int
+	// If positive, link statistics are printed to stderr with this period.
+	Stats time.Duration
+	// If set, only this CrazyRadio dongle is used. It's either the dongle serial number,
+	// or its key, like CrazyRadio-Bus:1-Address:5-v00.50.
+	Dongle string
+}
+
+// Flags registers -addr, -index, -stats and -dongle flags in fs.
+func Flags(fs *flag.FlagSet) *Target {
+	t := new(Target)
+	fs.StringVar(&t.Addr, "addr", "", "Crazyflie address, like radio://0/10/250K or radio://0/80/2M/E7E7E7E7E7. If empty, the spectrum is scanned")
+	fs.IntVar(&t.Index, "index", -1, "Which of the scanned Crazyflies to use (0-based). If not set and more than one is found, you will be asked")
+	fs.DurationVar(&t.Stats, "stats", 0, "If set, link statistics are printed to stderr with this period")
+	fs.StringVar(&t.Dongle, "dongle", "", "If set, only this CrazyRadio dongle is used: its serial number, or its key like CrazyRadio-Bus:1-Address:5-v00.50")
+	return t
+}
+
+// Connect starts a station and opens a link to the target Crazyflie.
+func (t *Target) Connect() (flie *cflie.Endpoint, flieAddr string, err error) {
+	if t.Addr != "" {
+		if _, _, err = cflie.ParseAddr(t.Addr); err != nil {
+			return nil, "", fmt.Errorf("Invalid address %q: %v", t.Addr, err)
+		}
+	}
+
+	st, err := cflie.Start(usb.Hub)
+	if err != nil {
+		return nil, "", fmt.Errorf("Unable to start station: %v", err)
+	}
+
+	flieAddr = t.Addr
+	if flieAddr == "" {
+		if flieAddr, err = t.scan(st); err != nil {
+			return nil, "", err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	flie, err = st.OpenContext(ctx, flieAddr, cflie.OpenOptions{Dongles: t.selector()})
+	if err != nil {
+		return nil, "", fmt.Errorf("Unable to connect to [%s]: %v", flieAddr, err)
+	}
+	if t.Stats > 0 {
+		go printStats(flie.WatchStats(t.Stats))
+	}
+	return flie, flieAddr, nil
+}
+
+func printStats(c <-chan cflie.LinkStats) {
+	for s := range c {
+		fmt.Fprintf(os.Stderr, "Link: quality %5.1f%%, sent %d, acked %d, retries %d, pings %d, payloads %d, dropped %d, RSSI %d dBm\n",
+			s.Quality, s.Sent, s.Acked, s.Retries, s.Pings, s.Payloads, s.Dropped, s.RSSI)
+	}
+}
+
+func (t *Target) selector() cflie.DongleSelector {
+	return cflie.DongleSelector{Dongle: t.Dongle}
+}
+
+func (t *Target) scan(st cflie.Station) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	list, err := st.ScanContext(ctx, cflie.ScanOptions{Dongles: t.selector()})
+	if err != nil {
+		return "", fmt.Errorf("Scan: %v", err)
+	}
+	if len(list) == 0 {
+		return "", fmt.Errorf("No Crazyflies found")
+	}
+	if t.Index >= 0 {
+		if t.Index >= len(list) {
+			return "", fmt.Errorf("-index=%d, but only %d Crazyflies found: %v", t.Index, len(list), list)
+		}
+		return list[t.Index], nil
+	}
+	if len(list) == 1 {
+		return list[0], nil
+	}
+	return choose(list)
+}
+
+func choose(list []string) (string, error) {
+	fmt.Fprintf(os.Stderr, "Found %d Crazyflies:\n", len(list))
+	for i, addr := range list {
+		fmt.Fprintf(os.Stderr, "%d. %s\n", i, addr)
+	}
+	for {
+		fmt.Fprintf(os.Stderr, "Which one to use? ")
+		var index int
+		if _, err := fmt.Fscanln(os.Stdin, &index); err != nil {
+			return "", fmt.Errorf("Unable to read the choice: %v", err)
+		}
+		if index >= 0 && index < len(list) {
+			return list[index], nil
+		}
+		fmt.Fprintf(os.Stderr, "Must be in range [0, %d]\n", len(list)-1)
+	}
+}
@@ -0,0 +1,116 @@
+// Lists and streams Crazyflie log variables.
+//
+// Usage:
+//
+//	cflie log [flags] list
+//	cflie log [flags] stream group.name...
+package log
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/samofly/cflie/crtp"
+	"github.com/samofly/cflie/pkg/flie"
+	"github.com/samofly/cflie/telemetry"
+	"github.com/samofly/cflie/toc"
+)
+
+var flags = flag.NewFlagSet("log", flag.ExitOnError)
+var target = flie.Flags(flags)
+var cache = flags.String("cache", flie.CacheDir, "Directory to cache log TOC in. If empty, TOC is always downloaded")
+var format = flags.String("format", "csv", "Output format: csv or json")
+var period = flags.Duration("period", 100*time.Millisecond, "Log period, 10ms..2.55s")
+
+func fail(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format, args...)
+	os.Exit(1)
+}
+
+func Main() {
+	flags.Parse(flag.Args()[1:])
+
+	sub := "list"
+	if flags.NArg() > 0 {
+		sub = flags.Arg(0)
+	}
+	switch {
+	case sub == "list" && flags.NArg() <= 1:
+	case sub == "stream" && flags.NArg() > 1:
+	default:
+		fail("Usage: cflie log [flags] list | stream group.name...\n")
+	}
+	if *format != "csv" && *format != "json" {
+		fail("Unknown format: %s\n", *format)
+	}
+
+	ep, _, err := target.Connect()
+	if err != nil {
+		fail("%v\n", err)
+	}
+	conn := crtp.NewConn(ep)
+	defer conn.Close()
+
+	var tocCache toc.Cache
+	if *cache != "" {
+		tocCache = toc.NewDirCache(*cache)
+	}
+	c, err := telemetry.New(conn, tocCache)
+	if err != nil {
+		fail("Unable to initialize logging: %v\n", err)
+	}
+
+	if sub == "list" {
+		for _, v := range c.Vars() {
+			fmt.Printf("%-40s %s\n", v.FullName(), v.Kind)
+		}
+		return
+	}
+
+	names := flags.Args()[1:]
+	b, err := c.Start(names, *period)
+	if err != nil {
+		fail("%v\n", err)
+	}
+	switch *format {
+	case "csv":
+		writeCSV(b, names)
+	case "json":
+		writeJSON(b)
+	}
+	fail("Connection lost: %v\n", conn.Err())
+}
+
+func writeCSV(b *telemetry.Block, names []string) {
+	w := csv.NewWriter(os.Stdout)
+	w.Write(append([]string{"timestamp_ms"}, names...))
+	w.Flush()
+	for s := range b.C {
+		row := []string{fmt.Sprint(int64(s.Timestamp / time.Millisecond))}
+		for _, name := range names {
+			row = append(row, fmt.Sprint(s.Values[name]))
+		}
+		w.Write(row)
+		w.Flush()
+		if err := w.Error(); err != nil {
+			fail("Unable to write output: %v\n", err)
+		}
+	}
+}
+
+func writeJSON(b *telemetry.Block) {
+	enc := json.NewEncoder(os.Stdout)
+	for s := range b.C {
+		err := enc.Encode(struct {
+			Timestamp int64                  `json:"timestamp_ms"`
+			Values    map[string]interface{} `json:"values"`
+		}{int64(s.Timestamp / time.Millisecond), s.Values})
+		if err != nil {
+			fail("Unable to write output: %v\n", err)
+		}
+	}
+}
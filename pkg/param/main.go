@@ -0,0 +1,86 @@
+// Lists, reads and writes Crazyflie firmware parameters.
+//
+// Usage:
+//
+//	cflie param [flags] list
+//	cflie param [flags] get group.name
+//	cflie param [flags] set group.name value
+package param
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/samofly/cflie/crtp"
+	"github.com/samofly/cflie/param"
+	"github.com/samofly/cflie/pkg/flie"
+	"github.com/samofly/cflie/toc"
+)
+
+var flags = flag.NewFlagSet("param", flag.ExitOnError)
+var target = flie.Flags(flags)
+var cache = flags.String("cache", flie.CacheDir, "Directory to cache param TOC in. If empty, TOC is always downloaded")
+
+func fail(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format, args...)
+	os.Exit(1)
+}
+
+func Main() {
+	flags.Parse(flag.Args()[1:])
+
+	sub := "list"
+	if flags.NArg() > 0 {
+		sub = flags.Arg(0)
+	}
+	switch {
+	case sub == "list" && flags.NArg() <= 1:
+	case sub == "get" && flags.NArg() == 2:
+	case sub == "set" && flags.NArg() == 3:
+	default:
+		fail("Usage: cflie param [flags] list | get group.name | set group.name value\n")
+	}
+
+	ep, _, err := target.Connect()
+	if err != nil {
+		fail("%v\n", err)
+	}
+	conn := crtp.NewConn(ep)
+	defer conn.Close()
+
+	var tocCache toc.Cache
+	if *cache != "" {
+		tocCache = toc.NewDirCache(*cache)
+	}
+	c, err := param.New(conn, tocCache)
+	if err != nil {
+		fail("Unable to download param TOC: %v\n", err)
+	}
+
+	switch sub {
+	case "list":
+		for _, v := range c.Vars() {
+			access := "RW"
+			if v.ReadOnly {
+				access = "RO"
+			}
+			fmt.Printf("%-40s %-7s %s\n", v.FullName(), v.Kind, access)
+		}
+	case "get":
+		v, err := c.Get(flags.Arg(1))
+		if err != nil {
+			fail("%v\n", err)
+		}
+		fmt.Printf("%s = %v\n", flags.Arg(1), v)
+	case "set":
+		if err = c.Set(flags.Arg(1), flags.Arg(2)); err != nil {
+			fail("%v\n", err)
+		}
+		v, err := c.Get(flags.Arg(1))
+		if err != nil {
+			fail("%v\n", err)
+		}
+		fmt.Printf("%s = %v\n", flags.Arg(1), v)
+	}
+}
@@ -2,54 +2,31 @@
 package play
 
 import (
-	"bytes"
-	"encoding/binary"
+	"flag"
 	"fmt"
 	"os"
 
-	"github.com/samofly/cflie"
-	"github.com/samofly/cflie/usb"
+	"github.com/samofly/cflie/commander"
+	"github.com/samofly/cflie/crtp"
+	"github.com/samofly/cflie/pkg/flie"
 )
 
+var flags = flag.NewFlagSet("play", flag.ExitOnError)
+var target = flie.Flags(flags)
+
 func fail(format string, args ...interface{}) {
 	fmt.Fprintf(os.Stderr, format, args...)
 	os.Exit(1)
 }
 
-func cmd(roll, pitch, yaw float32, thrust uint16) []byte {
-	var buf bytes.Buffer
-	buf.Write([]byte{60})
-	err := binary.Write(&buf, binary.LittleEndian,
-		struct {
-			roll, pitch, yaw float32
-			thrust           uint16
-		}{roll, pitch, yaw, thrust})
-	if err != nil {
-		panic(fmt.Sprintf("binary.Write: %v", err))
-	}
-	return buf.Bytes()
-}
-
 func Main() {
-	st, err := cflie.Start(usb.Hub)
-	if err != nil {
-		fail("Unable to start station: %v\n", err)
-	}
+	flags.Parse(flag.Args()[1:])
 
-	addr, err := st.Scan()
+	ep, _, err := target.Connect()
 	if err != nil {
-		fail("Scan: %v\n", err)
-	}
-
-	if len(addr) == 0 {
-		fail("No Crazyflies found\n")
-	}
-
-	flieAddr := addr[0]
-	flie, err := st.Open(flieAddr)
-	if err != nil {
-		fail("Unable to connect to [%s]: %v\n", flieAddr, err)
+		fail("%v\n", err)
 	}
+	cmd := commander.New(crtp.NewConn(ep), 0, 0)
 
 	for {
 
@@ -66,9 +43,12 @@ func Main() {
 		}
 		switch buf[0] {
 		case ' ':
-			flie.SendChan <- cmd(0, 0, 0, 37000)
+			err = cmd.Set(commander.Setpoint{Thrust: 37000})
 		default:
-			flie.SendChan <- cmd(0, 0, 0, 30000)
+			err = cmd.Set(commander.Setpoint{Thrust: 30000})
+		}
+		if err != nil {
+			fail("Unable to send a setpoint: %v\n", err)
 		}
 	}
 }
@@ -7,13 +7,13 @@ import (
 	"os"
 	"time"
 
-	"github.com/samofly/cflie"
-	"github.com/samofly/cflie/usb"
+	"github.com/samofly/cflie/pkg/flie"
 )
 
 var flags = flag.NewFlagSet("record", flag.ExitOnError)
 
 var output = flags.String("output", "", "File with saved incoming packets")
+var target = flie.Flags(flags)
 
 func fail(format string, args ...interface{}) {
 	fmt.Fprintf(os.Stderr, format, args...)
@@ -33,29 +33,13 @@ func Main() {
 	}
 	defer f.Close()
 
-	st, err := cflie.Start(usb.Hub)
+	ep, flieAddr, err := target.Connect()
 	if err != nil {
-		fail("Unable to start station: %v\n", err)
+		fail("%v\n", err)
 	}
+	fmt.Fprintf(os.Stderr, "Connected to %s\n", flieAddr)
 
-	addr, err := st.Scan()
-	if err != nil {
-		fail("Scan: %v\n", err)
-	}
-
-	if len(addr) == 0 {
-		fail("No Crazyflies found\n")
-	}
-
-	fmt.Fprintf(os.Stderr, "Found Crazyflies: %+v\n", addr)
-
-	flieAddr := addr[0]
-	flie, err := st.Open(flieAddr)
-	if err != nil {
-		fail("Unable to connect to [%s]: %v\n", flieAddr, err)
-	}
-
-	for p := range flie.RecvChan {
+	for p := range ep.RecvChan {
 		if len(p) == 0 {
 			continue
 		}
@@ -70,4 +54,5 @@ func Main() {
 			fail("Unable to flush output: %v\n", err)
 		}
 	}
+	fail("Link to %s is down: %v\n", flieAddr, ep.Err())
 }
@@ -2,20 +2,68 @@
 package scan
 
 import (
+	"context"
+	"flag"
+	"fmt"
 	"log"
+	"strings"
 
 	"github.com/samofly/cflie"
 	"github.com/samofly/cflie/usb"
 )
 
+var flags = flag.NewFlagSet("scan", flag.ExitOnError)
+var watch = flags.Duration("watch", 0, "If set, keep scanning with this interval and report Crazyflies as they appear and disappear")
+var radioAddrs = flags.String("radio_addrs", "", "Comma-separated list of radio addresses to probe, like E7E7E7E7E7,E7E7E7E701. If empty, the default address is used")
+
+func parseRadioAddrs(s string) (list [][5]byte, err error) {
+	if s == "" {
+		return [][5]byte{cflie.DefaultRadioAddress}, nil
+	}
+	for _, str := range strings.Split(s, ",") {
+		addr, err := cflie.ParseRadioAddress(str)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, addr)
+	}
+	return
+}
+
 func Main() {
+	flags.Parse(flag.Args()[1:])
+
+	list, err := parseRadioAddrs(*radioAddrs)
+	if err != nil {
+		log.Fatal(err)
+	}
 	st, err := cflie.Start(usb.Hub)
 	if err != nil {
 		log.Fatal(err)
 	}
-	addr, err := st.Scan()
+	if *watch > 0 {
+		watchScan(st, list)
+		return
+	}
+	addr, err := st.ScanContext(context.Background(), cflie.ScanOptions{RadioAddrs: list})
 	if err != nil {
 		log.Fatalf("Scan failed: %v", err)
 	}
 	log.Printf("Found Crazyflies: %v", addr)
 }
+
+func watchScan(st cflie.Station, list [][5]byte) {
+	events := st.Subscribe()
+	go func() {
+		err := st.Watch(context.Background(), cflie.WatchOptions{RadioAddrs: list, Interval: *watch})
+		log.Fatalf("Watch: %v", err)
+	}()
+	for e := range events {
+		switch e.Type {
+		case cflie.CrazyflieFound:
+			fmt.Printf("%s found %s\n", e.Time.Format("15:04:05"), e.Addr)
+		case cflie.CrazyflieLost:
+			fmt.Printf("%s lost  %s\n", e.Time.Format("15:04:05"), e.Addr)
+		}
+	}
+}
@@ -2,40 +2,39 @@
 package spin
 
 import (
+	"flag"
 	"fmt"
 	"os"
 	"time"
 
-	"github.com/samofly/cflie"
-	"github.com/samofly/cflie/usb"
+	"github.com/samofly/cflie/commander"
+	"github.com/samofly/cflie/crtp"
+	"github.com/samofly/cflie/pkg/flie"
 )
 
+var flags = flag.NewFlagSet("spin", flag.ExitOnError)
+var target = flie.Flags(flags)
+
 func fail(format string, args ...interface{}) {
 	fmt.Fprintf(os.Stderr, format, args...)
 	os.Exit(1)
 }
 
 func Main() {
-	st, err := cflie.Start(usb.Hub)
-	if err != nil {
-		fail("Unable to start station: %v\n", err)
-	}
+	flags.Parse(flag.Args()[1:])
 
-	addr, err := st.Scan()
+	ep, _, err := target.Connect()
 	if err != nil {
-		fail("Scan: %v\n", err)
-	}
-
-	if len(addr) == 0 {
-		fail("No Crazyflies found\n")
+		fail("%v\n", err)
 	}
-
-	flieAddr := addr[0]
-	flie, err := st.Open(flieAddr)
-	if err != nil {
-		fail("Unable to connect to [%s]: %v\n", flieAddr, err)
+	cmd := commander.New(crtp.NewConn(ep), 0, 0)
+	// The same setpoint spin has always sent, with yaw of about 2.96 degrees
+	if err = cmd.Set(commander.Setpoint{Yaw: 2.9603257, Thrust: 30000}); err != nil {
+		fail("Unable to send a setpoint: %v\n", err)
 	}
-	flie.SendChan <- []byte{60, 0, 0, 0, 0, 0, 0, 0, 128, 250, 117, 61, 64, 48, 117}
 
 	time.Sleep(500 * time.Millisecond)
+	if err = cmd.Close(); err != nil {
+		fail("Unable to stop motors: %v\n", err)
+	}
 }
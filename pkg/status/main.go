@@ -0,0 +1,51 @@
+// Shows CrazyRadio dongles and whether they can be opened.
+//
+// The command starts its own station, so it can't see what other processes
+// are doing: dongles used by them can't be opened and are shown as blacklisted,
+// along with the open error. Long running processes should log or otherwise
+// expose their own Station.Dongles instead.
+package status
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/samofly/cflie"
+	"github.com/samofly/cflie/usb"
+)
+
+var flags = flag.NewFlagSet("status", flag.ExitOnError)
+var wait = flags.Duration("wait", 2*time.Second, "How long to wait for dongles to be opened")
+
+func fail(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format, args...)
+	os.Exit(1)
+}
+
+func Main() {
+	flags.Parse(flag.Args()[1:])
+
+	st, err := cflie.Start(usb.Hub)
+	if err != nil {
+		fail("Unable to start station: %v\n", err)
+	}
+	defer st.Close()
+
+	var list []cflie.DongleStatus
+	for deadline := time.Now().Add(*wait); ; time.Sleep(100 * time.Millisecond) {
+		if list, err = st.Dongles(); err != nil {
+			fail("%v\n", err)
+		}
+		if len(list) > 0 || time.Now().After(deadline) {
+			break
+		}
+	}
+	if len(list) == 0 {
+		fail("No CrazyRadio dongles found\n")
+	}
+	for _, d := range list {
+		fmt.Println(d)
+	}
+}
@@ -0,0 +1,87 @@
+package cflie
+
+import (
+	"fmt"
+	"time"
+)
+
+// PollPolicy controls how often an Endpoint polls Crazyflie with empty packets
+// when there is nothing to send. Crazyflie can only send data in ACK payloads,
+// so the polling interval bounds the latency of downstream data.
+//
+// The endpoint polls every MinInterval while Crazyflie returns payloads.
+// Each empty ACK multiplies the interval by Backoff, up to MaxInterval.
+type PollPolicy struct {
+	// Zero means polling as fast as the dongle allows.
+	MinInterval time.Duration
+	// Must be less than Options.EndpointLostTimeout.
+	MaxInterval time.Duration
+	// Must be at least 1. Backoff of 1 means polling every MinInterval.
+	Backoff float64
+}
+
+// The first backoff step when MinInterval is zero
+const minPollBackoff = 500 * time.Microsecond
+
+// DefaultPollPolicy is the default of Options.PollPolicy.
+var DefaultPollPolicy = PollPolicy{
+	MinInterval: 0,
+	MaxInterval: 10 * time.Millisecond,
+	Backoff:     2,
+}
+
+// Validate returns an error if the policy can't be used with the default EndpointLostTimeout.
+func (p PollPolicy) Validate() error {
+	return p.validate(EndpointLostTimeout)
+}
+
+func (p PollPolicy) validate(lostTimeout time.Duration) error {
+	if p.MinInterval < 0 {
+		return fmt.Errorf("MinInterval must not be negative, got %v", p.MinInterval)
+	}
+	if p.MaxInterval < p.MinInterval {
+		return fmt.Errorf("MaxInterval (%v) must not be less than MinInterval (%v)", p.MaxInterval, p.MinInterval)
+	}
+	if p.MaxInterval >= lostTimeout {
+		return fmt.Errorf("MaxInterval must be less than %v, got %v", lostTimeout, p.MaxInterval)
+	}
+	if p.Backoff < 1 {
+		return fmt.Errorf("Backoff must be at least 1, got %v", p.Backoff)
+	}
+	return nil
+}
+
+// next returns the polling interval to use after a packet exchange.
+func (p PollPolicy) next(cur time.Duration, payload bool) time.Duration {
+	if payload {
+		return p.MinInterval
+	}
+	next := time.Duration(float64(cur) * p.Backoff)
+	if p.Backoff > 1 && next < minPollBackoff {
+		next = minPollBackoff
+	}
+	if next < p.MinInterval {
+		next = p.MinInterval
+	}
+	if next > p.MaxInterval {
+		next = p.MaxInterval
+	}
+	return next
+}
+
+// SetPollPolicy changes the polling policy of the endpoint.
+func (ep *Endpoint) SetPollPolicy(p PollPolicy) error {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+	if err := p.validate(ep.lostTimeout); err != nil {
+		return err
+	}
+	ep.poll = p
+	return nil
+}
+
+func (ep *Endpoint) pollPolicy() PollPolicy {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+	return ep.poll
+}
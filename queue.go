@@ -0,0 +1,72 @@
+package cflie
+
+import "container/heap"
+
+// Order priorities. Orders with higher priority are assigned to dongles first,
+// orders with the same priority are assigned in the order of arrival.
+const (
+	priorityBackground = iota
+	priorityScan
+	priorityOpen
+)
+
+// prioritizer is implemented by orders with a priority other than priorityScan.
+type prioritizer interface {
+	priority() int
+}
+
+func priority(order Order) int {
+	if p, ok := order.(prioritizer); ok {
+		return p.priority()
+	}
+	return priorityScan
+}
+
+type queuedOrder struct {
+	order    Order
+	priority int
+	// Arrival number
+	seq uint64
+}
+
+// orderQueue is a priority queue of pending orders. Use Push and Pop from container/heap.
+type orderQueue struct {
+	items []*queuedOrder
+	seq   uint64
+}
+
+func (q *orderQueue) Len() int { return len(q.items) }
+func (q *orderQueue) Less(i, j int) bool {
+	a, b := q.items[i], q.items[j]
+	if a.priority != b.priority {
+		return a.priority > b.priority
+	}
+	return a.seq < b.seq
+}
+func (q *orderQueue) Swap(i, j int) { q.items[i], q.items[j] = q.items[j], q.items[i] }
+func (q *orderQueue) Push(x interface{}) {
+	q.items = append(q.items, x.(*queuedOrder))
+}
+func (q *orderQueue) Pop() interface{} {
+	n := len(q.items)
+	item := q.items[n-1]
+	q.items[n-1] = nil
+	q.items = q.items[:n-1]
+	return item
+}
+
+// add queues a newly arrived order.
+func (q *orderQueue) add(order Order) {
+	q.seq++
+	heap.Push(q, &queuedOrder{order: order, priority: priority(order), seq: q.seq})
+}
+
+// next removes and returns the order with the highest priority.
+func (q *orderQueue) next() *queuedOrder {
+	return heap.Pop(q).(*queuedOrder)
+}
+
+// requeue puts back an order returned by next, keeping its place in the queue.
+func (q *orderQueue) requeue(item *queuedOrder) {
+	heap.Push(q, item)
+}
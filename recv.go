@@ -0,0 +1,94 @@
+package cflie
+
+import "fmt"
+
+// OverflowPolicy tells an Endpoint what to do with a packet received from Crazyflie
+// when the receive queue is full.
+type OverflowPolicy int
+
+const (
+	// Drop the oldest queued packet to make room for the new one
+	DropOldest OverflowPolicy = iota
+	// Drop the new packet
+	DropNewest
+	// Stop talking to Crazyflie until the receiver catches up.
+	// Other endpoints sharing the dongle are stalled, too.
+	Block
+)
+
+func (p OverflowPolicy) String() string {
+	switch p {
+	case DropOldest:
+		return "DropOldest"
+	case DropNewest:
+		return "DropNewest"
+	case Block:
+		return "Block"
+	}
+	return fmt.Sprintf("OverflowPolicy:#%d", int(p))
+}
+
+// RecvQueueSize is the default of OpenOptions.RecvQueueSize.
+const RecvQueueSize = 64
+
+// DefaultOverflowPolicy is the default of OpenOptions.Overflow.
+const DefaultOverflowPolicy = DropOldest
+
+func (p OverflowPolicy) validate() error {
+	if p < DropOldest || p > Block {
+		return fmt.Errorf("Unknown overflow policy: %v", p)
+	}
+	return nil
+}
+
+// SetOverflowPolicy changes what happens when RecvChan is full.
+// Dropped packets are counted in LinkStats.Dropped. The policy only applies
+// to RecvChan: slow subscribers of crtp.Conn still lose packets, see crtp.Conn.Dropped.
+func (ep *Endpoint) SetOverflowPolicy(p OverflowPolicy) error {
+	if err := p.validate(); err != nil {
+		return err
+	}
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+	ep.overflow = p
+	return nil
+}
+
+func (ep *Endpoint) overflowPolicy() OverflowPolicy {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+	return ep.overflow
+}
+
+// deliver puts a packet received from Crazyflie to the receive queue.
+// It only returns an error if the link must be torn down while blocked.
+func (d *dongle) deliver(ep *Endpoint, recvChan chan []byte, p []byte) error {
+	select {
+	case recvChan <- p:
+		return nil
+	default:
+	}
+	switch ep.overflowPolicy() {
+	case DropOldest:
+		// Only the dongle goroutine sends to recvChan, so there's room after one receive
+		select {
+		case <-recvChan:
+			ep.stats.drop()
+		default:
+		}
+		recvChan <- p
+	case DropNewest:
+		ep.stats.drop()
+	case Block:
+		select {
+		case recvChan <- p:
+		case <-d.quit:
+			return ErrStationClosed
+		case <-d.lost:
+			return ErrDongleLost
+		case <-ep.quit:
+			return ErrEndpointClosed
+		}
+	}
+	return nil
+}
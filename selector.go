@@ -0,0 +1,54 @@
+package cflie
+
+import "fmt"
+
+// DongleSelector chooses dongles which may process a scan or open an endpoint.
+// The zero value accepts any dongle.
+//
+// Dongles are identified by DeviceInfo.String(), or by serial number
+// if DeviceInfo implements Serial() string.
+type DongleSelector struct {
+	// If set, only this dongle is used
+	Dongle string
+	// Dongles which must not be used
+	Exclude []string
+	// If set, dongles with this firmware version, like "0.50", are preferred
+	Firmware string
+}
+
+// serialNumberer is implemented by DeviceInfo which knows the dongle serial number.
+type serialNumberer interface {
+	Serial() string
+}
+
+// FirmwareVersion returns the dongle firmware version, like "0.50".
+func FirmwareVersion(info DeviceInfo) string {
+	return fmt.Sprintf("%x.%02x", info.MajorVer(), info.MinorVer())
+}
+
+// identifies reports whether id is the key or the serial number of the dongle.
+func identifies(id string, info DeviceInfo) bool {
+	if id == info.String() {
+		return true
+	}
+	if s, ok := info.(serialNumberer); ok && s.Serial() != "" {
+		return id == s.Serial()
+	}
+	return false
+}
+
+func (sel DongleSelector) accepts(info DeviceInfo) bool {
+	if sel.Dongle != "" && !identifies(sel.Dongle, info) {
+		return false
+	}
+	for _, id := range sel.Exclude {
+		if identifies(id, info) {
+			return false
+		}
+	}
+	return true
+}
+
+func (sel DongleSelector) prefers(info DeviceInfo) bool {
+	return sel.Firmware != "" && FirmwareVersion(info) == sel.Firmware
+}
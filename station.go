@@ -1,9 +1,12 @@
 package cflie
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"io"
-	"log"
+	"sort"
+	"sync"
 	"time"
 )
 
@@ -12,33 +15,96 @@ type Hub interface {
 	Open(info DeviceInfo) (dev Device, err error)
 }
 
+// BlackListDuration is the default of Options.BlackListDuration.
 const BlackListDuration = 5 * time.Second
-const scanChunkTimeout = 10 * time.Second
-const openEndpointDeadline = 5 * time.Second
 
-var emptyPacket = []byte{0xFF}
+// ScanChunkChannels is the default of Options.ScanChunkChannels.
+const ScanChunkChannels = 32
+
+// How many different dongles may try to scan a chunk before the scan fails.
+const maxScanChunkAttempts = 3
+
+var ErrStationClosed = fmt.Errorf("Station is closed")
 
 type Order interface {
-	Deadline() time.Time
+	// Context of the order. Once it's done, the order is failed
+	// unless it's already being processed by a dongle.
+	Context() context.Context
 	Fail(err error)
 }
 
-type Endpoint struct {
-	RecvChan <-chan []byte
-	SendChan chan<- []byte
+// dongleFilter is implemented by orders which can't be processed by some dongles,
+// or should rather be processed by some of them.
+type dongleFilter interface {
+	accepts(info DeviceInfo) bool
+	prefers(info DeviceInfo) bool
+}
+
+func accepts(order Order, info DeviceInfo) bool {
+	if f, ok := order.(dongleFilter); ok {
+		return f.accepts(info)
+	}
+	return true
+}
+
+func prefers(order Order, info DeviceInfo) bool {
+	if f, ok := order.(dongleFilter); ok {
+		return f.prefers(info)
+	}
+	return false
 }
 
 type Station interface {
+	// Scan finds Crazyflies listening on DefaultRadioAddress.
 	Scan() (addr []string, err error)
+	// ScanContext finds Crazyflies as configured by opts. The scan is aborted
+	// once ctx is done; if ctx has no deadline, Options.ScanChunkTimeout applies
+	// per rate and radio address. Chunks which are already being scanned
+	// by dongles are allowed to complete.
+	ScanContext(ctx context.Context, opts ScanOptions) (addr []string, err error)
 	Open(addr string) (ep *Endpoint, err error)
+	// OpenContext is like Open, but gives up once ctx is done and uses the dongles
+	// chosen by opts. Once the endpoint is opened, ctx does not affect it.
+	OpenContext(ctx context.Context, addr string, opts OpenOptions) (ep *Endpoint, err error)
+	// Close stops tracking dongles, closes all endpoints, fails pending orders
+	// with ErrStationClosed and closes all dongles. It returns once all
+	// the station goroutines have exited.
+	Close() error
+	// Subscribe returns a channel which receives station events. If the subscriber
+	// does not keep up, extra events are dropped. The channel is closed
+	// on Unsubscribe, or once the station is closed.
+	Subscribe() <-chan Event
+	Unsubscribe(c <-chan Event)
+	// Dongles returns the snapshot of dongles opened or blacklisted by this station.
+	// Dongles used by other processes are reported as blacklisted.
+	Dongles() ([]DongleStatus, error)
+	// Watch keeps scanning in background with idle dongles and emits CrazyflieFound
+	// and CrazyflieLost events, until ctx is done or the station is closed.
+	Watch(ctx context.Context, opts WatchOptions) error
 }
 
+// Start starts a station with the default options.
 func Start(hub Hub) (Station, error) {
+	return StartWithOptions(hub, Options{})
+}
+
+// StartWithOptions starts a station which uses the dongles of the hub.
+func StartWithOptions(hub Hub, opts Options) (Station, error) {
 	if hub == nil {
 		panic("hub == nil")
 	}
+	opts = opts.withDefaults()
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
 	st := &station{hub: hub,
+		opts:       opts,
 		ordersChan: make(chan Order),
+		quit:       make(chan bool),
+		done:       make(chan bool),
+		events:     new(eventHub),
+		statusChan: make(chan chan []DongleStatus),
+		log:        opts.Logger,
 	}
 	go st.run()
 	return st, nil
@@ -49,250 +115,412 @@ type station struct {
 	lsChan     <-chan []DeviceInfo
 	ordersChan chan Order
 	s          *scheduler
+	// Closed by Close
+	quit      chan bool
+	closeOnce sync.Once
+	// Closed when all the goroutines have exited
+	done       chan bool
+	events     *eventHub
+	statusChan chan chan []DongleStatus
+	opts       Options
+	log        Logger
 }
 
 func (st *station) run() {
+	defer close(st.done)
+	defer st.events.close()
 	dongleErrChan := make(chan error, 10)
 	cancelTrackChan := make(chan bool)
-	st.lsChan = st.hub.ListPush(cancelTrackChan, dongleErrChan)
+	if h, ok := st.hub.(IntervalHub); ok && st.opts.ListInterval > 0 {
+		st.lsChan = h.ListPushEvery(st.opts.ListInterval, cancelTrackChan, dongleErrChan)
+	} else {
+		st.lsChan = st.hub.ListPush(cancelTrackChan, dongleErrChan)
+	}
 	scheduleErrChan := make(chan error, 10)
-	st.s = newScheduler(st.hub, st.lsChan, st.ordersChan, scheduleErrChan)
+	st.s = newScheduler(st.hub, st.lsChan, st.ordersChan, scheduleErrChan, st.quit, st.events, st.opts)
+	st.s.statusChan = st.statusChan
 	go st.s.run()
-	for {
+	quit := st.quit
+	// Both the hub and the scheduler close their error channels on exit
+	for dongleErrChan != nil || scheduleErrChan != nil {
 		select {
-		case err := <-dongleErrChan:
-			log.Printf("Dongle tracker error: %v", err)
-		case err := <-scheduleErrChan:
-			log.Printf("Schedule error: %v", err)
+		case err, ok := <-dongleErrChan:
+			if !ok {
+				dongleErrChan = nil
+				continue
+			}
+			st.log.Warn("Dongle tracker error", "err", err)
+			st.events.emit(Event{Type: StationError, Err: err})
+		case err, ok := <-scheduleErrChan:
+			if !ok {
+				scheduleErrChan = nil
+				continue
+			}
+			st.log.Warn("Schedule error", "err", err)
+			st.events.emit(Event{Type: StationError, Err: err})
+		case <-quit:
+			close(cancelTrackChan)
+			quit = nil
 		}
 	}
 }
 
+func (st *station) Close() error {
+	err := ErrStationClosed
+	st.closeOnce.Do(func() {
+		close(st.quit)
+		err = nil
+	})
+	<-st.done
+	return err
+}
+
+func (st *station) Subscribe() <-chan Event {
+	return st.events.subscribe()
+}
+
+func (st *station) Unsubscribe(c <-chan Event) {
+	st.events.unsubscribe(c)
+}
+
 type scheduler struct {
-	hub           Hub
-	lsChan        <-chan []DeviceInfo
-	ordersChan    chan Order
-	errChan       chan<- error
-	opened        map[string]Device
-	dongleChans   map[string]chan Order
-	readyChan     chan string
-	ready         map[string]bool
-	failed        map[string]time.Time
-	pendingOrders []Order
-}
-
-func newScheduler(hub Hub, lsChan <-chan []DeviceInfo, ordersChan chan Order, errChan chan<- error) *scheduler {
+	hub        Hub
+	lsChan     <-chan []DeviceInfo
+	ordersChan chan Order
+	errChan    chan<- error
+	quit       <-chan bool
+	events     *eventHub
+	opts       Options
+	log        Logger
+	opened     map[string]Device
+	infos      map[string]DeviceInfo
+	running    map[string]*dongle
+	readyChan  chan dongleState
+	ready      map[string]bool
+	// Number of endpoints served by each dongle
+	endpoints map[string]int
+	// Orders being processed by dongles
+	assigned map[string]Order
+	failed   map[string]failure
+	// Requests for status snapshots, see Station.Dongles
+	statusChan    <-chan chan []DongleStatus
+	pendingOrders orderQueue
+	// Tracks dongle goroutines
+	dongles sync.WaitGroup
+}
+
+func newScheduler(hub Hub, lsChan <-chan []DeviceInfo, ordersChan chan Order, errChan chan<- error, quit <-chan bool, events *eventHub, opts Options) *scheduler {
 	return &scheduler{
-		hub:         hub,
-		lsChan:      lsChan,
-		ordersChan:  ordersChan,
-		errChan:     errChan,
-		opened:      make(map[string]Device),
-		dongleChans: make(map[string]chan Order),
-		readyChan:   make(chan string),
-		ready:       make(map[string]bool),
-		failed:      make(map[string]time.Time),
+		events:     events,
+		opts:       opts,
+		log:        opts.Logger,
+		hub:        hub,
+		lsChan:     lsChan,
+		ordersChan: ordersChan,
+		errChan:    errChan,
+		quit:       quit,
+		opened:     make(map[string]Device),
+		infos:      make(map[string]DeviceInfo),
+		running:    make(map[string]*dongle),
+		readyChan:  make(chan dongleState),
+		ready:      make(map[string]bool),
+		endpoints:  make(map[string]int),
+		assigned:   make(map[string]Order),
+		failed:     make(map[string]failure),
 	}
 }
 
 func (s *scheduler) run() {
+	defer close(s.errChan)
+	tick := time.NewTicker(s.opts.SchedulerTick)
+	defer tick.Stop()
 	for {
 		select {
-		case list := <-s.lsChan:
+		case list, ok := <-s.lsChan:
+			if !ok {
+				// Dongle tracking is stopped
+				s.lsChan = nil
+				continue
+			}
 			s.updateDonglesList(list)
-		case key := <-s.readyChan:
-			s.markReady(key)
+		case state := <-s.readyChan:
+			s.markReady(state)
 		case order := <-s.ordersChan:
-			s.pendingOrders = append(s.pendingOrders, order)
-		case <-time.After(time.Second):
+			s.pendingOrders.add(order)
+		case resp := <-s.statusChan:
+			resp <- s.status()
+		case <-tick.C:
 			// To make sure that timed-out orders are marked as failed
+		case <-s.quit:
+			s.shutdown()
+			return
 		}
 		s.processPendingOrders()
 	}
 }
 
+// shutdown fails all pending orders, stops dongle goroutines and closes dongles.
+func (s *scheduler) shutdown() {
+	for s.pendingOrders.Len() > 0 {
+		order := s.pendingOrders.next().order
+		order.Fail(ErrStationClosed)
+		s.events.emit(orderEvent(OrderFailed, order, "", ErrStationClosed))
+	}
+	for key, d := range s.running {
+		close(d.ordersChan)
+		delete(s.running, key)
+	}
+	// Dongle goroutines close their endpoints once quit is closed,
+	// and then close their devices.
+	s.dongles.Wait()
+	for key := range s.opened {
+		delete(s.opened, key)
+		delete(s.infos, key)
+		delete(s.ready, key)
+		delete(s.endpoints, key)
+		delete(s.assigned, key)
+	}
+}
+
 func (s *scheduler) assign(dongleKey string, order Order) {
-	// Assumes that dongleKey is ready
-	s.dongleChans[dongleKey] <- order
+	// Assumes that dongleKey is ready.
+	// The event goes first, the dongle may emit its own events right away.
+	s.events.emit(orderEvent(OrderAssigned, order, dongleKey, nil))
+	s.running[dongleKey].ordersChan <- order
 	delete(s.ready, dongleKey)
+	s.assigned[dongleKey] = order
 }
 
+// processPendingOrders assigns pending orders to ready dongles in the order of priority.
+// Timed out and cancelled orders are reported as failed.
 func (s *scheduler) processPendingOrders() {
-	// First, report all timed out orders
-	for i, order := range s.pendingOrders {
-		if order == nil {
+	var rest []*queuedOrder
+	for s.pendingOrders.Len() > 0 {
+		item := s.pendingOrders.next()
+		order := item.order
+		if err := orderErr(order); err != nil {
+			order.Fail(err)
+			s.events.emit(orderEvent(OrderTimedOut, order, "", err))
 			continue
 		}
-		if time.Now().After(order.Deadline()) {
-			order.Fail(fmt.Errorf("Order timed out"))
-			s.pendingOrders[i] = nil
+		if key, ok := s.pickDongle(order); ok {
+			s.assign(key, order)
 			continue
 		}
+		if !s.canProcess(order) {
+			err := fmt.Errorf("No dongle can process the order")
+			order.Fail(err)
+			s.events.emit(orderEvent(OrderFailed, order, "", err))
+			continue
+		}
+		rest = append(rest, item)
+	}
+	for _, item := range rest {
+		s.pendingOrders.requeue(item)
+	}
+}
+
+// orderErr returns a non-nil error if the order context is done.
+func orderErr(order Order) error {
+	switch err := order.Context().Err(); err {
+	case nil:
+		return nil
+	case context.DeadlineExceeded:
+		return fmt.Errorf("Order timed out")
+	default:
+		return err
 	}
+}
 
-	// Assign pending orders while we have ready dongles
-	for _, order := range s.pendingOrders {
-		if len(s.ready) == 0 {
-			return
+// pickDongle finds a ready dongle accepted by the order, preferring
+// the dongles the order prefers. Endpoints are opened on the least loaded
+// dongle, while other orders need a dongle which is not serving any endpoints.
+func (s *scheduler) pickDongle(order Order) (key string, ok bool) {
+	_, open := order.(*openEndpointOrder)
+	limit := 1
+	if open {
+		limit = s.opts.MaxEndpointsPerDongle
+	}
+	best := false
+	for cur := range s.ready {
+		info := s.infos[cur]
+		if !accepts(order, info) || s.endpoints[cur] >= limit {
+			continue
 		}
-		s.pendingOrders = s.pendingOrders[1:]
-		if order == nil {
+		pref := prefers(order, info)
+		if ok && (best && !pref || best == pref && s.endpoints[cur] >= s.endpoints[key]) {
 			continue
 		}
-		for key := range s.ready {
-			s.assign(key, order)
-			break
+		key, ok, best = cur, true, pref
+	}
+	return
+}
+
+// canProcess reports whether any of the opened dongles is accepted by the order.
+// If there are no dongles at all, the order may wait for them to be plugged in.
+func (s *scheduler) canProcess(order Order) bool {
+	if len(s.opened) == 0 {
+		return true
+	}
+	for _, info := range s.infos {
+		if accepts(order, info) {
+			return true
 		}
 	}
+	return false
 }
 
-func (s *scheduler) markReady(key string) {
+// failure records when a dongle could not be opened.
+type failure struct {
+	info DeviceInfo
+	at   time.Time
+	err  error
+}
+
+func (s *scheduler) markReady(state dongleState) {
 	// It might be that the dongle is already closed, but the message
 	// that it's ready is just arrived. Ignore such message.
-	if _, ok := s.opened[key]; ok {
-		s.ready[key] = true
+	if _, ok := s.opened[state.key]; !ok {
+		return
+	}
+	s.endpoints[state.key] = state.endpoints
+	if state.ready {
+		s.ready[state.key] = true
+		delete(s.assigned, state.key)
 	}
 }
 
+// openDongle opens the dongle and applies the radio config, if any.
+func (s *scheduler) openDongle(info DeviceInfo) (Device, error) {
+	dev, err := s.hub.Open(info)
+	if err != nil || s.opts.Radio == nil {
+		return dev, err
+	}
+	c, ok := dev.(RadioConfigurer)
+	if !ok {
+		s.log.Warn("Dongle does not support radio config", "dongle", info)
+		return dev, nil
+	}
+	if err = c.SetRadioConfig(*s.opts.Radio); err != nil {
+		dev.Close()
+		return nil, fmt.Errorf("Unable to configure %s: %v", info, err)
+	}
+	return dev, nil
+}
+
 func (s *scheduler) updateDonglesList(list []DeviceInfo) {
 	found := make(map[string]bool)
 	for _, info := range list {
 		key := info.String()
 		found[key] = true
 		if _, ok := s.opened[key]; !ok {
-			if failTime, ok := s.failed[key]; ok && time.Now().Sub(failTime) < BlackListDuration {
+			if f, ok := s.failed[key]; ok && time.Now().Sub(f.at) < s.opts.BlackListDuration {
 				continue
 			}
-			dev, err := s.hub.Open(info)
+			dev, err := s.openDongle(info)
 			if err != nil {
-				s.failed[key] = time.Now()
+				s.failed[key] = failure{info: info, at: time.Now(), err: err}
 				s.errChan <- err
+				s.events.emit(Event{Type: DongleFailed, Dongle: key, Err: err})
 				continue
 			}
-			dongleChan := make(chan Order, 1)
+			d := &dongle{
+				key:        key,
+				dev:        dev,
+				ordersChan: make(chan Order, 1),
+				readyChan:  s.readyChan,
+				retryChan:  s.ordersChan,
+				errChan:    s.errChan,
+				quit:       s.quit,
+				lost:       make(chan bool),
+				buf:        make([]byte, 64),
+				events:     s.events,
+				opts:       s.opts,
+				log:        s.log,
+			}
 			s.opened[key] = dev
+			s.infos[key] = info
 			s.ready[key] = true
-			s.dongleChans[key] = dongleChan
-			log.Printf("About to start runDongle")
-			go runDongle(key, dev, dongleChan, s.readyChan)
-			log.Printf("Opened %s", key)
+			s.running[key] = d
+			s.log.Debug("Starting dongle goroutine", "dongle", key)
+			s.dongles.Add(1)
+			go func() {
+				defer s.dongles.Done()
+				d.run()
+			}()
+			s.log.Info("Dongle opened", "dongle", key)
+			s.events.emit(Event{Type: DongleAdded, Dongle: key})
 		}
 	}
-	for key, dev := range s.opened {
+	for key := range s.opened {
 		if !found[key] {
+			// The dongle goroutine owns the device: it closes the endpoint
+			// (if any) with ErrDongleLost, and then closes the device.
+			if order, ok := s.assigned[key]; ok {
+				s.log.Warn("Dongle lost while processing an order", "dongle", key, "order", order)
+			}
+			if n := s.endpoints[key]; n > 0 {
+				s.log.Warn("Dongle lost while serving endpoints", "dongle", key, "endpoints", n)
+			}
 			delete(s.opened, key)
+			delete(s.infos, key)
 			delete(s.ready, key)
-			if ch, ok := s.dongleChans[key]; ok {
-				close(ch)
-				delete(s.dongleChans, key)
-			}
-			if err := dev.Close(); err != nil {
-				s.errChan <- err
+			delete(s.endpoints, key)
+			delete(s.assigned, key)
+			if d, ok := s.running[key]; ok {
+				close(d.lost)
+				close(d.ordersChan)
+				delete(s.running, key)
 			}
-			log.Printf("Lost %s", key)
+			s.log.Info("Dongle lost", "dongle", key)
+			s.events.emit(Event{Type: DongleLost, Dongle: key})
 		}
 	}
 }
 
-func processDongleOrder(dev Device, order Order) {
-	switch order.(type) {
-	case *scanChunkOrder:
-		cur := order.(*scanChunkOrder)
-		addr, err := dev.ScanChunk(cur.rate, cur.fromCh, cur.toCh)
-		if err != nil {
-			cur.respCh <- &scanChunkResp{err: err}
-			return
-		}
-		log.Printf("runDongle, report result: %v", addr)
-		cur.respCh <- &scanChunkResp{addr: addr}
-	case *openEndpointOrder:
-		cur := order.(*openEndpointOrder)
-		doOpenEndpoint(dev, cur)
-	default:
-		order.Fail(fmt.Errorf("Unknown order type: %T", order))
-	}
-
+type scanChunkOrder struct {
+	ctx       context.Context
+	rate      DataRate
+	fromCh    uint8
+	toCh      uint8
+	radioAddr [5]byte
+	sel       DongleSelector
+	// Background chunks are only scanned when there are no other orders
+	background bool
+	respCh     chan *scanChunkResp
+	// Dongles which failed to scan this chunk
+	failed  map[string]bool
+	lastErr error
 }
 
-func doOpenEndpoint(dev Device, order *openEndpointOrder) {
-	err := dev.SetRateAndChannel(order.rate, order.ch)
-	if err != nil {
-		order.Fail(err)
-		return
-	}
-	recvChan := make(chan []byte)
-	sendChan := make(chan []byte)
-	order.respChan <- &openEndpointResp{
-		ep: &Endpoint{
-			RecvChan: recvChan,
-			SendChan: sendChan,
-		},
-	}
-
-	buf := make([]byte, 64)
-
-	for {
-		var p []byte
-		var ok bool
-		select {
-		case p, ok = <-sendChan:
-			if !ok {
-				close(recvChan)
-				return
-			}
-		default:
-			p = emptyPacket
-		}
-		_, err = dev.Write(p)
-		if err != nil {
-			// TODO: send error to somewhere
-			log.Printf("Unable to write to device: %v", err)
-		}
-
-		// Read reply
-		n, err := dev.Read(buf)
-		if err != nil {
-			log.Printf("Error: reader: %v", err)
-			continue
-		}
-		p = make([]byte, n)
-		copy(p, buf)
-		// Cut off the ACK byte
-		if len(p) >= 1 {
-			p = p[1:]
-		}
+func (o *scanChunkOrder) Context() context.Context {
+	return o.ctx
+}
 
-		// If there's no receiver, just drop the packet.
-		select {
-		case recvChan <- p:
-		default:
-		}
-	}
+func (o *scanChunkOrder) String() string {
+	return fmt.Sprintf("scan %s channels %d-%d at %X", o.rate, o.fromCh, o.toCh, o.radioAddr)
 }
 
-func runDongle(key string, dev Device, ordersChan chan Order, readyChan chan string) {
-	log.Printf("runDongle, 0")
-	for order := range ordersChan {
-		log.Printf("runDongle, got order: %T %v", order, order)
-		processDongleOrder(dev, order)
-		readyChan <- key
+func (o *scanChunkOrder) Fail(err error) {
+	if o.lastErr != nil && err != o.lastErr {
+		err = fmt.Errorf("%v (last attempt: %v)", err, o.lastErr)
 	}
+	o.respCh <- &scanChunkResp{err: err}
 }
 
-type scanChunkOrder struct {
-	deadline time.Time
-	rate     DataRate
-	fromCh   uint8
-	toCh     uint8
-	respCh   chan *scanChunkResp
+func (o *scanChunkOrder) accepts(info DeviceInfo) bool {
+	return !o.failed[info.String()] && o.sel.accepts(info)
 }
 
-func (o *scanChunkOrder) Deadline() time.Time {
-	return o.deadline
+func (o *scanChunkOrder) prefers(info DeviceInfo) bool {
+	return o.sel.prefers(info)
 }
 
-func (o *scanChunkOrder) Fail(err error) {
-	o.respCh <- &scanChunkResp{err: err}
+func (o *scanChunkOrder) priority() int {
+	if o.background {
+		return priorityBackground
+	}
+	return priorityScan
 }
 
 type scanChunkResp struct {
@@ -301,22 +529,45 @@ type scanChunkResp struct {
 }
 
 func (st *station) Scan() (addr []string, err error) {
-	respCh := make(chan *scanChunkResp, len(Rates))
+	return st.ScanContext(context.Background(), ScanOptions{})
+}
+
+func (st *station) ScanContext(ctx context.Context, opts ScanOptions) (addr []string, err error) {
+	if opts.RadioAddrs == nil {
+		opts.RadioAddrs = [][5]byte{DefaultRadioAddress}
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		// Even with a single dongle, all the chunks must have a chance to be scanned.
+		timeout := st.opts.ScanChunkTimeout * time.Duration(len(Rates)*len(opts.RadioAddrs))
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	orders := scanOrders(ctx, opts.RadioAddrs, st.opts.ScanChunkChannels)
+	// Buffered, so that orders could be failed after we stop waiting for them.
+	respCh := make(chan *scanChunkResp, len(orders))
 	var errors []error
-	for _, rate := range Rates {
-		order := &scanChunkOrder{
-			deadline: time.Now().Add(scanChunkTimeout),
-			rate:     rate,
-			fromCh:   0,
-			toCh:     MaxChannel,
-			respCh:   respCh,
+	for _, order := range orders {
+		order.respCh = respCh
+		order.sel = opts.Dongles
+		order.background = opts.Background
+		st.log.Debug("Sending an order", "order", order)
+		select {
+		case st.ordersChan <- order:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-st.quit:
+			return nil, ErrStationClosed
 		}
-		log.Printf("Sending an order: %+v", order)
-		st.ordersChan <- order
-		log.Printf("Order sent")
+		st.log.Debug("Order sent", "order", order)
 	}
-	for _ = range Rates {
-		resp := <-respCh
+	for _ = range orders {
+		var resp *scanChunkResp
+		select {
+		case resp = <-respCh:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
 		if resp.err != nil {
 			errors = append(errors, resp.err)
 			continue
@@ -327,18 +578,85 @@ func (st *station) Scan() (addr []string, err error) {
 		// Just return the first error
 		return nil, errors[0]
 	}
+	// Chunks complete in arbitrary order
+	sort.Sort(byRadioURI(addr))
+	return
+}
+
+// scanOrders splits the spectrum into chunks of the specified number of channels
+// for every rate and radio address, so that idle dongles could scan them in parallel.
+func scanOrders(ctx context.Context, radioAddrs [][5]byte, chunk int) (orders []*scanChunkOrder) {
+	for _, radioAddr := range radioAddrs {
+		for _, rate := range Rates {
+			for fromCh := 0; fromCh < MaxChannel; fromCh += chunk {
+				toCh := fromCh + chunk
+				if toCh > MaxChannel {
+					toCh = MaxChannel
+				}
+				orders = append(orders, &scanChunkOrder{
+					ctx:       ctx,
+					rate:      rate,
+					fromCh:    uint8(fromCh),
+					toCh:      uint8(toCh),
+					radioAddr: radioAddr,
+					failed:    make(map[string]bool),
+				})
+			}
+		}
+	}
 	return
 }
 
+// byRadioURI sorts radio URIs by rate, channel and radio address.
+type byRadioURI []string
+
+func (s byRadioURI) Len() int      { return len(s) }
+func (s byRadioURI) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s byRadioURI) Less(i, j int) bool {
+	rate1, ch1, addr1, err1 := ParseURI(s[i])
+	rate2, ch2, addr2, err2 := ParseURI(s[j])
+	if err1 != nil || err2 != nil {
+		return s[i] < s[j]
+	}
+	if rate1 != rate2 {
+		return rate1 < rate2
+	}
+	if ch1 != ch2 {
+		return ch1 < ch2
+	}
+	return bytes.Compare(addr1[:], addr2[:]) < 0
+}
+
 type openEndpointOrder struct {
-	deadline time.Time
-	rate     DataRate
-	ch       uint8
-	respChan chan *openEndpointResp
+	ctx       context.Context
+	rate      DataRate
+	ch        uint8
+	radioAddr [5]byte
+	sel       DongleSelector
+	queueSize int
+	overflow  OverflowPolicy
+	respChan  chan *openEndpointResp
+}
+
+func (o *openEndpointOrder) Context() context.Context {
+	return o.ctx
+}
+
+func (o *openEndpointOrder) accepts(info DeviceInfo) bool {
+	return o.sel.accepts(info)
+}
+
+func (o *openEndpointOrder) prefers(info DeviceInfo) bool {
+	return o.sel.prefers(info)
+}
+
+// Opening an endpoint takes precedence over queued scan chunks.
+func (o *openEndpointOrder) priority() int {
+	return priorityOpen
 }
 
-func (o *openEndpointOrder) Deadline() time.Time {
-	return o.deadline
+func (o *openEndpointOrder) String() string {
+	return "open " + RadioURI(o.rate, o.ch, o.radioAddr)
 }
 
 func (o *openEndpointOrder) Fail(err error) {
@@ -351,18 +669,54 @@ type openEndpointResp struct {
 }
 
 func (st *station) Open(addr string) (ep *Endpoint, err error) {
-	rate, ch, err := ParseAddr(addr)
+	ctx, cancel := context.WithTimeout(context.Background(), st.opts.OpenTimeout)
+	defer cancel()
+	return st.OpenContext(ctx, addr, OpenOptions{})
+}
+
+func (st *station) OpenContext(ctx context.Context, addr string, opts OpenOptions) (ep *Endpoint, err error) {
+	rate, ch, radioAddr, err := ParseURI(addr)
 	if err != nil {
 		return
 	}
+	if err = opts.Overflow.validate(); err != nil {
+		return
+	}
+	if opts.RecvQueueSize <= 0 {
+		opts.RecvQueueSize = RecvQueueSize
+	}
 	respChan := make(chan *openEndpointResp, 1)
-	st.ordersChan <- &openEndpointOrder{
-		deadline: time.Now().Add(openEndpointDeadline),
-		rate:     rate,
-		ch:       ch,
-		respChan: respChan,
+	order := &openEndpointOrder{
+		ctx:       ctx,
+		rate:      rate,
+		ch:        ch,
+		radioAddr: radioAddr,
+		sel:       opts.Dongles,
+		queueSize: opts.RecvQueueSize,
+		overflow:  opts.Overflow,
+		respChan:  respChan,
+	}
+	select {
+	case st.ordersChan <- order:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-st.quit:
+		return nil, ErrStationClosed
+	}
+	var resp *openEndpointResp
+	var ok bool
+	select {
+	case resp, ok = <-respChan:
+	case <-ctx.Done():
+		// The order is either failed by the scheduler or the dongle, or the endpoint
+		// is opened anyway. In the latter case, close it to release the dongle.
+		go func() {
+			if resp, ok := <-respChan; ok && resp.ep != nil {
+				resp.ep.Close()
+			}
+		}()
+		return nil, ctx.Err()
 	}
-	resp, ok := <-respChan
 	if !ok {
 		return nil, io.EOF
 	}
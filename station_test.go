@@ -1,21 +1,50 @@
 package cflie
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 type testHub struct {
 	info *testDeviceInfo
+	more []*testDeviceInfo
+	// If not nil, dongles are only reported once plug is closed
+	plug chan bool
+	// If not nil, closing unplug makes all dongles disappear
+	unplug chan bool
 }
 
 func (h *testHub) ListPush(cancelChan <-chan bool, errChan chan<- error) <-chan []DeviceInfo {
 	lsChan := make(chan []DeviceInfo)
 	go func() {
-		lsChan <- []DeviceInfo{h.info}
-		<-cancelChan
+		list := []DeviceInfo{h.info}
+		for _, info := range h.more {
+			list = append(list, info)
+		}
+		if h.plug != nil {
+			select {
+			case <-h.plug:
+			case <-cancelChan:
+			}
+		}
+		select {
+		case lsChan <- list:
+			select {
+			case <-h.unplug:
+				select {
+				case lsChan <- nil:
+				case <-cancelChan:
+				}
+				<-cancelChan
+			case <-cancelChan:
+			}
+		case <-cancelChan:
+		}
 		close(lsChan)
 		close(errChan)
 	}()
@@ -27,35 +56,121 @@ func (h *testHub) Open(info DeviceInfo) (dev Device, err error) {
 	if !ok {
 		return nil, fmt.Errorf("Unexpected deviceInfo: %T", info)
 	}
+	if testInfo.openErr != nil {
+		return nil, testInfo.openErr
+	}
 	return testInfo.dev, nil
 }
 
 type testDeviceInfo struct {
-	dev *testDevice
+	dev  *testDevice
+	name string
+	// Firmware minor version, 0x50 if not set
+	minor int
+	// If set, the hub fails to open the dongle
+	openErr error
+	serial  string
 }
 
 func (di *testDeviceInfo) Bus() int       { return 1 }
 func (di *testDeviceInfo) Address() int   { return 1 }
 func (di *testDeviceInfo) MajorVer() int  { return 0 }
-func (di *testDeviceInfo) MinorVer() int  { return 0x50 }
-func (di *testDeviceInfo) String() string { return "test device info" }
+func (di *testDeviceInfo) Serial() string { return di.serial }
+func (di *testDeviceInfo) MinorVer() int {
+	if di.minor != 0 {
+		return di.minor
+	}
+	return 0x50
+}
+func (di *testDeviceInfo) String() string {
+	if di.name != "" {
+		return di.name
+	}
+	return "test device info"
+}
 
 type testDevice struct {
-	info *testDeviceInfo
+	info      *testDeviceInfo
+	scanErr   error
+	scanDelay time.Duration
+
+	mu     sync.Mutex
+	closed bool
+	// Radio link emulation
+	noAck   bool
+	readErr error
+	// If set, ACKs carry a single byte payload, incremented on every Read
+	payloads bool
+	seq      byte
+	radio    *RadioConfig
+	// If set, scans find nothing
+	hidden bool
+}
+
+func (d *testDevice) SetRadioConfig(cfg RadioConfig) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.radio = &cfg
+	return nil
 }
 
-func (d *testDevice) Close() error { return nil }
+func (d *testDevice) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.closed = true
+	return nil
+}
+
+func (d *testDevice) isClosed() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.closed
+}
+
+// Read returns a status byte: acknowledged, unless noAck is set.
 func (d *testDevice) Read(p []byte) (n int, err error) {
-	panic("testDevice.Read not implemented")
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.readErr != nil {
+		return 0, d.readErr
+	}
+	p[0] = 1
+	if d.noAck {
+		p[0] = 0
+	}
+	if d.payloads {
+		p[1] = d.seq
+		d.seq++
+		return 2, nil
+	}
+	return 1, nil
 }
+
 func (d *testDevice) Write(p []byte) (n int, err error) {
-	panic("testDevice.Write not implemented")
+	return len(p), nil
+}
+
+func (d *testDevice) setLink(noAck bool, readErr error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.noAck = noAck
+	d.readErr = readErr
 }
 func (d *testDevice) Scan() (addr []string, err error) {
 	panic("testDevice.Scan not implemented")
 }
 
 func (d *testDevice) ScanChunk(rate DataRate, fromCh, toCh uint8) (addr []string, err error) {
+	time.Sleep(d.scanDelay)
+	if d.scanErr != nil {
+		return nil, d.scanErr
+	}
+	d.mu.Lock()
+	hidden := d.hidden
+	d.mu.Unlock()
+	if hidden {
+		return nil, nil
+	}
 	switch rate {
 	case DATA_RATE_250K:
 		if fromCh <= 10 && toCh > 10 {
@@ -70,6 +185,62 @@ func (d *testDevice) ScanChunk(rate DataRate, fromCh, toCh uint8) (addr []string
 }
 
 func (d *testDevice) SetRateAndChannel(rate DataRate, ch uint8) error { return nil }
+func (d *testDevice) SetRadioAddress(addr [5]byte) error              { return nil }
+
+// startTestStation starts a station with a single dongle, which is closed with the station
+// once the test is done. If dev is nil, the dongle is a new testDevice.
+func startTestStation(t *testing.T, dev *testDevice) Station {
+	t.Helper()
+	if dev == nil {
+		dev = &testDevice{}
+	}
+	st, err := Start(&testHub{info: &testDeviceInfo{dev: dev}})
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(func() { st.Close() })
+	return st
+}
+
+// openTestEndpoint opens radio://0/10/250K, which is closed once the test is done.
+func openTestEndpoint(t *testing.T, st Station, opts OpenOptions) *Endpoint {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ep, err := st.OpenContext(ctx, "radio://0/10/250K", opts)
+	if err != nil {
+		t.Fatalf("OpenContext: %v", err)
+	}
+	t.Cleanup(func() { ep.Close() })
+	return ep
+}
+
+// waitFor polls cond until it holds. The test fails if it takes more than 5s.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); !cond(); time.Sleep(5 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", what)
+		}
+	}
+}
+
+// waitEvent skips events until there's one of the specified type.
+// The test fails if it takes more than 5s.
+func waitEvent(t *testing.T, events <-chan Event, typ EventType) Event {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case e := <-events:
+			if e.Type == typ {
+				return e
+			}
+		case <-timeout:
+			t.Fatalf("No %v event", typ)
+		}
+	}
+}
 
 func TestScan(t *testing.T) {
 	info := &testDeviceInfo{dev: &testDevice{}}
@@ -78,6 +249,7 @@ func TestScan(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Start: %v", err)
 	}
+	defer st.Close()
 	log.Printf("before Scan")
 	list, err := st.Scan()
 	log.Printf("after Scan")
@@ -89,3 +261,417 @@ func TestScan(t *testing.T) {
 		t.Errorf("Unexpected result. Want: %v, got: %v", want, list)
 	}
 }
+
+func TestScanRetry(t *testing.T) {
+	info := &testDeviceInfo{dev: &testDevice{}, name: "good"}
+	broken := &testDeviceInfo{dev: &testDevice{scanErr: fmt.Errorf("broken")}, name: "broken"}
+	hub := &testHub{info: info, more: []*testDeviceInfo{broken}}
+	st, err := Start(hub)
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer st.Close()
+	list, err := st.Scan()
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	want := []string{"radio://0/10/250K", "radio://0/24/1M"}
+	if strings.Join(want, ";") != strings.Join(list, ";") {
+		t.Errorf("Unexpected result. Want: %v, got: %v", want, list)
+	}
+}
+
+// emptyHub never reports any dongles.
+type emptyHub struct{}
+
+func (h emptyHub) ListPush(cancelChan <-chan bool, errChan chan<- error) <-chan []DeviceInfo {
+	lsChan := make(chan []DeviceInfo)
+	go func() {
+		<-cancelChan
+		close(lsChan)
+		close(errChan)
+	}()
+	return lsChan
+}
+
+func (h emptyHub) Open(info DeviceInfo) (dev Device, err error) {
+	return nil, fmt.Errorf("emptyHub.Open must not be called")
+}
+
+func TestScanContext(t *testing.T) {
+	st, err := Start(emptyHub{})
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer st.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err = st.ScanContext(ctx, ScanOptions{}); err != context.DeadlineExceeded {
+		t.Errorf("ScanContext: want context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Now().Sub(start); elapsed > time.Second {
+		t.Errorf("ScanContext must return once ctx is done, but it took %v", elapsed)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if _, err = st.OpenContext(ctx, "radio://0/10/250K", OpenOptions{}); err != context.Canceled {
+		t.Errorf("OpenContext: want context.Canceled, got %v", err)
+	}
+}
+
+func TestClose(t *testing.T) {
+	info := &testDeviceInfo{dev: &testDevice{}}
+	st, err := Start(&testHub{info: info})
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if _, err = st.Scan(); err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	if err = st.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if !info.dev.isClosed() {
+		t.Errorf("Dongle must be closed")
+	}
+	if err = st.Close(); err != ErrStationClosed {
+		t.Errorf("Second Close: want ErrStationClosed, got %v", err)
+	}
+	if _, err = st.Scan(); err != ErrStationClosed {
+		t.Errorf("Scan after Close: want ErrStationClosed, got %v", err)
+	}
+}
+
+func TestDongleSelector(t *testing.T) {
+	// Crazyflie does not respond via "deaf", so endpoints can only be opened via "good"
+	deaf := &testDeviceInfo{dev: &testDevice{noAck: true}, name: "deaf"}
+	good := &testDeviceInfo{dev: &testDevice{}, name: "good", minor: 0x51, serial: "E7A1C0DE"}
+	st, err := Start(&testHub{info: deaf, more: []*testDeviceInfo{good}})
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer st.Close()
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		ep, err := st.OpenContext(ctx, "radio://0/10/250K", OpenOptions{Dongles: DongleSelector{Exclude: []string{"deaf"}}})
+		if err != nil {
+			t.Fatalf("OpenContext, excluding deaf: %v", err)
+		}
+		ep.Close()
+		ep, err = st.OpenContext(ctx, "radio://0/10/250K", OpenOptions{Dongles: DongleSelector{Firmware: "0.51"}})
+		if err != nil {
+			t.Fatalf("OpenContext, preferring 0.51: %v", err)
+		}
+		ep.Close()
+	}
+	if _, err = st.OpenContext(ctx, "radio://0/10/250K", OpenOptions{Dongles: DongleSelector{Dongle: "deaf"}}); err != ErrNoAck {
+		t.Errorf("OpenContext deaf: want ErrNoAck, got %v", err)
+	}
+	ep, err := st.OpenContext(ctx, "radio://0/10/250K", OpenOptions{Dongles: DongleSelector{Dongle: "E7A1C0DE"}})
+	if err != nil {
+		t.Fatalf("OpenContext by serial: %v", err)
+	}
+	ep.Close()
+	if _, err = st.OpenContext(ctx, "radio://0/10/250K", OpenOptions{Dongles: DongleSelector{Exclude: []string{"E7A1C0DE"}}}); err != ErrNoAck {
+		t.Errorf("OpenContext excluding good by serial: want ErrNoAck, got %v", err)
+	}
+	if _, err = st.OpenContext(ctx, "radio://0/10/250K", OpenOptions{Dongles: DongleSelector{Dongle: "missing"}}); err == nil {
+		t.Errorf("OpenContext must fail if the dongle is missing")
+	}
+	list, err := st.ScanContext(ctx, ScanOptions{Dongles: DongleSelector{Dongle: "deaf"}})
+	if err != nil {
+		t.Fatalf("ScanContext: %v", err)
+	}
+	if len(list) != 2 {
+		t.Errorf("Unexpected scan result: %v", list)
+	}
+}
+
+func TestOrderQueue(t *testing.T) {
+	ctx := context.Background()
+	bg := &scanChunkOrder{ctx: ctx, background: true}
+	scan1 := &scanChunkOrder{ctx: ctx}
+	scan2 := &scanChunkOrder{ctx: ctx}
+	open := &openEndpointOrder{ctx: ctx}
+	var q orderQueue
+	for _, order := range []Order{bg, scan1, open, scan2} {
+		q.add(order)
+	}
+	// Requeued orders keep their place
+	item := q.next()
+	q.requeue(item)
+	for i, want := range []Order{open, scan1, scan2, bg} {
+		if got := q.next().order; got != want {
+			t.Errorf("#%d: want %T %p, got %T %p", i, want, want, got, got)
+		}
+	}
+	if q.Len() != 0 {
+		t.Errorf("Queue must be empty, it has %d orders", q.Len())
+	}
+}
+
+func TestOpenPreemptsScan(t *testing.T) {
+	st := startTestStation(t, &testDevice{scanDelay: 20 * time.Millisecond})
+	events := st.Subscribe()
+
+	scanned := make(chan bool)
+	go func() {
+		st.Scan()
+		close(scanned)
+	}()
+	// The rest of the scan chunks are queued
+	waitEvent(t, events, OrderAssigned)
+	ep := openTestEndpoint(t, st, OpenOptions{})
+	select {
+	case <-scanned:
+		t.Errorf("Open must not wait for all the queued scan chunks")
+	default:
+	}
+	ep.Close()
+	<-scanned
+}
+
+func TestEvents(t *testing.T) {
+	hub := &testHub{info: &testDeviceInfo{dev: &testDevice{}}, plug: make(chan bool), unplug: make(chan bool)}
+	st, err := Start(hub)
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	events := st.Subscribe()
+	close(hub.plug)
+	ep, err := st.Open("radio://0/10/250K")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	ep.Close()
+	close(hub.unplug)
+
+	want := []Event{
+		{Type: DongleAdded, Dongle: "test device info"},
+		{Type: OrderAssigned, Dongle: "test device info", Order: "open radio://0/10/250K"},
+		{Type: EndpointOpened, Dongle: "test device info", Addr: "radio://0/10/250K"},
+		{Type: EndpointClosed, Dongle: "test device info", Addr: "radio://0/10/250K", Err: ErrEndpointClosed},
+		{Type: DongleLost, Dongle: "test device info"},
+	}
+	for i, w := range want {
+		e := <-events
+		e.Time = time.Time{}
+		if e != w {
+			t.Errorf("Event #%d: want %v, got %v", i, w, e)
+		}
+	}
+	st.Close()
+	if e, ok := <-events; ok {
+		t.Errorf("Events must be closed with the station, got %v", e)
+	}
+}
+
+// testLogger records messages by level.
+type testLogger struct {
+	mu   sync.Mutex
+	msgs map[string][]string
+}
+
+func (l *testLogger) add(level, msg string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.msgs == nil {
+		l.msgs = make(map[string][]string)
+	}
+	l.msgs[level] = append(l.msgs[level], msg)
+}
+
+func (l *testLogger) Debug(msg string, args ...interface{}) { l.add("debug", msg) }
+func (l *testLogger) Info(msg string, args ...interface{})  { l.add("info", msg) }
+func (l *testLogger) Warn(msg string, args ...interface{})  { l.add("warn", msg) }
+func (l *testLogger) Error(msg string, args ...interface{}) { l.add("error", msg) }
+
+func TestLogger(t *testing.T) {
+	logger := new(testLogger)
+	st, err := StartWithOptions(&testHub{info: &testDeviceInfo{dev: &testDevice{}}}, Options{Logger: logger})
+	if err != nil {
+		t.Fatalf("StartWithOptions: %v", err)
+	}
+	ep, err := st.Open("radio://0/10/250K")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	ep.Close()
+	st.Close()
+
+	logger.mu.Lock()
+	defer logger.mu.Unlock()
+	if want := []string{"Dongle opened"}; fmt.Sprint(logger.msgs["info"]) != fmt.Sprint(want) {
+		t.Errorf("Unexpected info messages. Want: %v, got: %v", want, logger.msgs["info"])
+	}
+	if len(logger.msgs["warn"])+len(logger.msgs["error"]) > 0 {
+		t.Errorf("Unexpected warnings: %v, errors: %v", logger.msgs["warn"], logger.msgs["error"])
+	}
+	if len(logger.msgs["debug"]) == 0 {
+		t.Errorf("No debug messages")
+	}
+}
+
+func TestOptions(t *testing.T) {
+	st, err := StartWithOptions(emptyHub{}, Options{OpenTimeout: 50 * time.Millisecond, SchedulerTick: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("StartWithOptions: %v", err)
+	}
+	start := time.Now()
+	if _, err = st.Open("radio://0/10/250K"); err != context.DeadlineExceeded {
+		t.Errorf("Open: want context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Now().Sub(start); elapsed > time.Second {
+		t.Errorf("Open must time out after OpenTimeout, but it took %v", elapsed)
+	}
+	st.Close()
+
+	for _, bad := range []Options{
+		{Radio: &RadioConfig{Power: 4}},
+		{Radio: &RadioConfig{ARC: 16}},
+		{ScanChunkChannels: MaxChannel + 1},
+	} {
+		if _, err = StartWithOptions(emptyHub{}, bad); err == nil {
+			t.Errorf("StartWithOptions must fail for %+v", bad)
+		}
+	}
+
+	dev := &testDevice{}
+	radio := RadioConfig{Power: 1, ARC: 3, ARD: 5}
+	st, err = StartWithOptions(&testHub{info: &testDeviceInfo{dev: dev}}, Options{Radio: &radio})
+	if err != nil {
+		t.Fatalf("StartWithOptions: %v", err)
+	}
+	defer st.Close()
+	ep, err := st.Open("radio://0/10/250K")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	ep.Close()
+	dev.mu.Lock()
+	defer dev.mu.Unlock()
+	if dev.radio == nil || *dev.radio != radio {
+		t.Errorf("Radio config is not applied. Want: %+v, got: %+v", radio, dev.radio)
+	}
+}
+
+func TestEndpointOptions(t *testing.T) {
+	opts := Options{EndpointLostTimeout: 100 * time.Millisecond, PollPolicy: PollPolicy{MaxInterval: time.Second, Backoff: 2}}
+	if _, err := StartWithOptions(emptyHub{}, opts); err == nil {
+		t.Errorf("StartWithOptions must fail if PollPolicy.MaxInterval is not less than EndpointLostTimeout")
+	}
+
+	opts = Options{MaxEndpointsPerDongle: 1, EndpointLostTimeout: 100 * time.Millisecond, OpenTimeout: 100 * time.Millisecond}
+	st, err := StartWithOptions(&testHub{info: &testDeviceInfo{dev: &testDevice{}}}, opts)
+	if err != nil {
+		t.Fatalf("StartWithOptions: %v", err)
+	}
+	defer st.Close()
+	ep, err := st.Open("radio://0/10/250K")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer ep.Close()
+	if err = ep.SetPollPolicy(PollPolicy{MaxInterval: 100 * time.Millisecond, Backoff: 2}); err == nil {
+		t.Errorf("SetPollPolicy must fail if MaxInterval is not less than Options.EndpointLostTimeout")
+	}
+	if _, err = st.Open("radio://0/20/250K"); err == nil {
+		t.Errorf("Open must fail once Options.MaxEndpointsPerDongle endpoints share the dongle")
+	}
+}
+
+func TestDongles(t *testing.T) {
+	good := &testDeviceInfo{dev: &testDevice{}, name: "good"}
+	broken := &testDeviceInfo{name: "broken", openErr: fmt.Errorf("broken")}
+	st, err := Start(&testHub{info: good, more: []*testDeviceInfo{broken}})
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer st.Close()
+
+	var list []DongleStatus
+	update := func() {
+		if list, err = st.Dongles(); err != nil {
+			t.Fatalf("Dongles: %v", err)
+		}
+	}
+	waitFor(t, "dongles to be listed", func() bool { update(); return len(list) == 2 })
+	if list[0].Key != "broken" || list[0].State != DongleBlacklisted || list[0].BlacklistedUntil.IsZero() || list[0].Err == nil {
+		t.Errorf("Broken dongle must be blacklisted: %+v", list[0])
+	}
+	if s := list[0].String(); !strings.HasPrefix(s, "broken v0.50 blacklisted until ") || !strings.HasSuffix(s, ": broken") {
+		t.Errorf("Unexpected status of the broken dongle: %s", s)
+	}
+	if list[1].Key != "good" || list[1].State != DongleIdle || list[1].Info != good {
+		t.Errorf("Good dongle must be idle: %+v", list[1])
+	}
+
+	openTestEndpoint(t, st, OpenOptions{})
+	// The dongle reports that it's ready right after the endpoint is opened
+	waitFor(t, "the good dongle to serve the endpoint", func() bool { update(); return list[1].State == DongleServing })
+	if list[1].Endpoints != 1 {
+		t.Errorf("Good dongle must be serving 1 endpoint: %+v", list[1])
+	}
+
+	st.Close()
+	if _, err = st.Dongles(); err != ErrStationClosed {
+		t.Errorf("Dongles after Close: want ErrStationClosed, got %v", err)
+	}
+}
+
+func TestPresence(t *testing.T) {
+	p := &presence{misses: 2, missed: make(map[string]int)}
+	a, b := "radio://0/10/250K", "radio://0/24/1M"
+	steps := []struct {
+		found          []string
+		appeared, lost []string
+	}{
+		{found: []string{b, a}, appeared: []string{a, b}},
+		{found: []string{a}},
+		// A single missed scan does not flap
+		{found: []string{a, b}},
+		{found: nil},
+		{found: nil, lost: []string{a, b}},
+		{found: []string{b}, appeared: []string{b}},
+	}
+	for i, step := range steps {
+		appeared, lost := p.update(step.found)
+		if fmt.Sprint(appeared) != fmt.Sprint(step.appeared) || fmt.Sprint(lost) != fmt.Sprint(step.lost) {
+			t.Errorf("Step #%d: want appeared %v, lost %v; got appeared %v, lost %v",
+				i, step.appeared, step.lost, appeared, lost)
+		}
+	}
+}
+
+func TestWatch(t *testing.T) {
+	dev := &testDevice{}
+	st := startTestStation(t, dev)
+	events := st.Subscribe()
+	ctx, cancel := context.WithCancel(context.Background())
+	watchErr := make(chan error, 1)
+	go func() {
+		watchErr <- st.Watch(ctx, WatchOptions{Interval: 10 * time.Millisecond, Misses: 2})
+	}()
+
+	for _, addr := range []string{"radio://0/10/250K", "radio://0/24/1M"} {
+		if e := waitEvent(t, events, CrazyflieFound); e.Addr != addr {
+			t.Errorf("CrazyflieFound: want %s, got %v", addr, e)
+		}
+	}
+	dev.mu.Lock()
+	dev.hidden = true
+	dev.mu.Unlock()
+	for _, addr := range []string{"radio://0/10/250K", "radio://0/24/1M"} {
+		if e := waitEvent(t, events, CrazyflieLost); e.Addr != addr {
+			t.Errorf("CrazyflieLost: want %s, got %v", addr, e)
+		}
+	}
+
+	cancel()
+	if err := <-watchErr; err != context.Canceled {
+		t.Errorf("Watch: want context.Canceled, got %v", err)
+	}
+}
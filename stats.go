@@ -0,0 +1,128 @@
+package cflie
+
+import (
+	"sync"
+	"time"
+)
+
+// Link quality is the share of acknowledged packets among this many last sent ones
+const linkQualityWindow = 100
+
+// LinkStats is a snapshot of the radio link counters of an Endpoint.
+type LinkStats struct {
+	// When the snapshot was taken
+	Time time.Time
+	// Packets written to the dongle, including empty pings
+	Sent uint64
+	// Packets acknowledged by Crazyflie
+	Acked uint64
+	// Retransmissions reported by the dongle in the ACK status byte
+	Retries uint64
+	// Empty packets sent to poll Crazyflie
+	Pings uint64
+	// Non-empty packets received from Crazyflie
+	Payloads uint64
+	// Packets received from Crazyflie, but dropped because RecvChan was full
+	Dropped uint64
+	// Percentage of acknowledged packets among the last linkQualityWindow sent ones
+	Quality float64
+	// Signal strength in dBm (negative), as last reported by Crazyflie.
+	// Zero if Crazyflie has never reported it.
+	RSSI int
+}
+
+// linkStats accumulates LinkStats. It's updated by the dongle goroutine.
+type linkStats struct {
+	mu  sync.Mutex
+	s   LinkStats
+	win [linkQualityWindow]bool
+	pos int
+	n   int
+	// Number of acknowledged packets in win
+	acked int
+}
+
+// record accounts for a single packet exchange. status is the ACK status byte,
+// reply is the ACK payload without the status byte.
+func (ls *linkStats) record(ping bool, status byte, reply []byte) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	ls.s.Sent++
+	if ping {
+		ls.s.Pings++
+	}
+	ack, retries := ackStatus(status)
+	ls.s.Retries += uint64(retries)
+	if ack {
+		ls.s.Acked++
+		if len(reply) > 0 {
+			ls.s.Payloads++
+		}
+		// Crazyflie reports RSSI in null packets on the link port: [0xF3|link bits, 0x01, -dBm]
+		if len(reply) >= 3 && reply[0]&0xF3 == 0xF3 && reply[1] == 0x01 {
+			ls.s.RSSI = -int(reply[2])
+		}
+	}
+
+	if ls.n == linkQualityWindow {
+		if ls.win[ls.pos] {
+			ls.acked--
+		}
+	} else {
+		ls.n++
+	}
+	ls.win[ls.pos] = ack
+	if ack {
+		ls.acked++
+	}
+	ls.pos = (ls.pos + 1) % linkQualityWindow
+}
+
+func (ls *linkStats) drop() {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	ls.s.Dropped++
+}
+
+func (ls *linkStats) snapshot() LinkStats {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	s := ls.s
+	s.Time = time.Now()
+	if ls.n > 0 {
+		s.Quality = 100 * float64(ls.acked) / float64(ls.n)
+	}
+	return s
+}
+
+// Stats returns the current link counters.
+func (ep *Endpoint) Stats() LinkStats {
+	return ep.stats.snapshot()
+}
+
+// WatchStats returns a channel which receives a snapshot of link counters every period.
+// Snapshots are dropped if the receiver is not ready. The channel is closed once the link is down.
+func (ep *Endpoint) WatchStats(period time.Duration) <-chan LinkStats {
+	c := make(chan LinkStats, 1)
+	if ep.done == nil {
+		close(c)
+		return c
+	}
+	go func() {
+		defer close(c)
+		ticker := time.NewTicker(period)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ep.done:
+				return
+			case <-ticker.C:
+				select {
+				case c <- ep.Stats():
+				default:
+				}
+			}
+		}
+	}()
+	return c
+}
@@ -0,0 +1,110 @@
+package cflie
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// DongleState tells what a dongle is doing.
+type DongleState int
+
+const (
+	// Waiting for orders
+	DongleIdle DongleState = iota
+	// Serving endpoints, while able to take more orders
+	DongleServing
+	// Processing an order
+	DongleBusy
+	// Could not be opened, ignored until BlacklistedUntil
+	DongleBlacklisted
+)
+
+func (s DongleState) String() string {
+	switch s {
+	case DongleIdle:
+		return "idle"
+	case DongleServing:
+		return "serving"
+	case DongleBusy:
+		return "busy"
+	case DongleBlacklisted:
+		return "blacklisted"
+	}
+	return fmt.Sprintf("DongleState:#%d", int(s))
+}
+
+// DongleStatus is a snapshot of a dongle known to a Station.
+type DongleStatus struct {
+	// DeviceInfo.String()
+	Key   string
+	Info  DeviceInfo
+	State DongleState
+	// Description of the order being processed, like "open radio://0/10/250K"
+	Order string
+	// Number of endpoints served by the dongle
+	Endpoints int
+	// Set for blacklisted dongles
+	BlacklistedUntil time.Time
+	// Why a blacklisted dongle could not be opened. Dongles used by other
+	// processes can't be opened, so they are blacklisted, too.
+	Err error
+}
+
+// String describes the dongle in a single line, so that long running processes
+// could log or otherwise expose Station.Dongles.
+func (d DongleStatus) String() string {
+	name := d.Key
+	if sn, ok := d.Info.(serialNumberer); ok && sn.Serial() != "" {
+		name += " serial:" + sn.Serial()
+	}
+	s := fmt.Sprintf("%s v%s %s", name, FirmwareVersion(d.Info), d.State)
+	switch d.State {
+	case DongleBusy:
+		s += " " + d.Order
+	case DongleServing:
+		s += fmt.Sprintf(" %d endpoints", d.Endpoints)
+	case DongleBlacklisted:
+		s += fmt.Sprintf(" until %s: %v", d.BlacklistedUntil.Format("15:04:05"), d.Err)
+	}
+	return s
+}
+
+// status returns the snapshot of all opened and blacklisted dongles, sorted by key.
+func (s *scheduler) status() (list []DongleStatus) {
+	for key, info := range s.infos {
+		st := DongleStatus{Key: key, Info: info, Endpoints: s.endpoints[key]}
+		switch {
+		case !s.ready[key]:
+			st.State = DongleBusy
+			if order, ok := s.assigned[key]; ok {
+				st.Order = fmt.Sprint(order)
+			}
+		case st.Endpoints > 0:
+			st.State = DongleServing
+		default:
+			st.State = DongleIdle
+		}
+		list = append(list, st)
+	}
+	now := time.Now()
+	for key, f := range s.failed {
+		until := f.at.Add(s.opts.BlackListDuration)
+		if _, ok := s.infos[key]; ok || !now.Before(until) {
+			continue
+		}
+		list = append(list, DongleStatus{Key: key, Info: f.info, State: DongleBlacklisted, BlacklistedUntil: until, Err: f.err})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Key < list[j].Key })
+	return
+}
+
+func (st *station) Dongles() ([]DongleStatus, error) {
+	resp := make(chan []DongleStatus, 1)
+	select {
+	case st.statusChan <- resp:
+	case <-st.quit:
+		return nil, ErrStationClosed
+	}
+	return <-resp, nil
+}
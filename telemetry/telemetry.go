@@ -0,0 +1,318 @@
+// Package telemetry is a client for the Crazyflie log subsystem.
+//
+// Firmware log variables (e.g. "stabilizer.roll") are grouped into log blocks.
+// Once a block is started, Crazyflie sends the values of its variables
+// periodically; the client decodes them into timestamped samples.
+//
+// Log port channels:
+//
+//	0: TOC access (see package toc)
+//	1: block control: [cmd, block id, ...] -> [cmd, block id, errno]
+//	2: data: [block id, timestamp (3 bytes LE, ms), values...]
+package telemetry
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/samofly/cflie/crtp"
+	"github.com/samofly/cflie/toc"
+)
+
+const (
+	ControlChannel = 1
+	DataChannel    = 2
+
+	CMD_CREATE_BLOCK  = 0
+	CMD_APPEND_BLOCK  = 1
+	CMD_DELETE_BLOCK  = 2
+	CMD_START_LOGGING = 3
+	CMD_STOP_LOGGING  = 4
+	CMD_RESET_LOGGING = 5
+
+	// Log period is sent in units of 10 ms.
+	periodUnit = 10 * time.Millisecond
+	MinPeriod  = periodUnit
+	MaxPeriod  = 255 * periodUnit
+
+	// Data packet header: block id + 3 bytes of timestamp.
+	dataHeaderSize = 4
+	// Each variable takes 2 bytes in the create block request.
+	MaxBlockVars = (crtp.MaxPayload - 2) / 2
+
+	sampleQueueSize = 64
+)
+
+// Errno is an error code returned by Crazyflie in a block control reply.
+type Errno uint8
+
+const (
+	ENOENT Errno = 2
+	EEXIST Errno = 17
+)
+
+func (e Errno) Error() string {
+	return fmt.Sprintf("Crazyflie returned error code %d", uint8(e))
+}
+
+// Log type codes as reported in the TOC.
+var kinds = map[uint8]toc.Kind{
+	1: toc.Uint8,
+	2: toc.Uint16,
+	3: toc.Uint32,
+	4: toc.Int8,
+	5: toc.Int16,
+	6: toc.Int32,
+	7: toc.Float32,
+	8: toc.Float16,
+}
+
+type Var struct {
+	toc.Element
+	Kind toc.Kind
+}
+
+// Sample is a set of values of a log block captured at the same moment.
+type Sample struct {
+	// Time since Crazyflie startup, as reported by the firmware (ms precision).
+	Timestamp time.Duration
+	// Local time when the sample was received.
+	Received time.Time
+	// Values by "group.name". Each value has a Go type matching the log variable type.
+	Values map[string]interface{}
+}
+
+type Block struct {
+	ID     uint8
+	Vars   []*Var
+	Period time.Duration
+	// C delivers decoded samples. It's closed when the block is stopped or the connection is lost.
+	// If the consumer does not keep up, extra samples are dropped, see Dropped.
+	C <-chan Sample
+
+	c       chan Sample
+	client  *Client
+	stopped bool
+	dropped int
+}
+
+type Client struct {
+	conn *crtp.Conn
+	toc  *toc.TOC
+	vars map[string]*Var
+
+	mu     sync.Mutex
+	blocks map[uint8]*Block
+	nextID uint8
+}
+
+// New downloads the log TOC (or takes it from cache, if not nil),
+// removes all log blocks left from previous sessions and starts receiving log data.
+func New(conn *crtp.Conn, cache toc.Cache) (*Client, error) {
+	t, err := toc.Fetch(conn, crtp.PortLog, cache)
+	if err != nil {
+		return nil, err
+	}
+	c := &Client{
+		conn:   conn,
+		toc:    t,
+		vars:   make(map[string]*Var),
+		blocks: make(map[uint8]*Block),
+	}
+	for _, e := range t.Elements {
+		kind, ok := kinds[e.Type]
+		if !ok {
+			kind = toc.Invalid
+		}
+		c.vars[e.FullName()] = &Var{Element: e, Kind: kind}
+	}
+	if err = c.control([]byte{CMD_RESET_LOGGING}, 0); err != nil {
+		return nil, fmt.Errorf("Unable to reset logging: %v", err)
+	}
+	go c.run(conn.Subscribe(crtp.PortLog, DataChannel))
+	return c, nil
+}
+
+// CRC returns the firmware CRC of the log TOC.
+func (c *Client) CRC() uint32 {
+	return c.toc.CRC
+}
+
+// Vars returns all log variables sorted by name.
+func (c *Client) Vars() []*Var {
+	var list []*Var
+	for _, v := range c.vars {
+		list = append(list, v)
+	}
+	sort.Sort(byName(list))
+	return list
+}
+
+// Lookup finds a log variable by "group.name".
+func (c *Client) Lookup(name string) (*Var, error) {
+	v, ok := c.vars[name]
+	if !ok {
+		return nil, fmt.Errorf("Unknown log variable: %s", name)
+	}
+	if v.Kind == toc.Invalid {
+		return nil, fmt.Errorf("Log variable %s has unsupported type: 0x%02X", name, v.Type)
+	}
+	return v, nil
+}
+
+// Start creates a log block with the specified variables and starts logging
+// with the given period (rounded down to 10 ms).
+func (c *Client) Start(names []string, period time.Duration) (*Block, error) {
+	if len(names) == 0 {
+		return nil, fmt.Errorf("No log variables specified")
+	}
+	if len(names) > MaxBlockVars {
+		return nil, fmt.Errorf("Too many log variables: %d, max: %d", len(names), MaxBlockVars)
+	}
+	if period < MinPeriod || period > MaxPeriod {
+		return nil, fmt.Errorf("Log period must be in range [%v, %v], got: %v", MinPeriod, MaxPeriod, period)
+	}
+	var vars []*Var
+	size := 0
+	req := []byte{CMD_CREATE_BLOCK, 0}
+	for _, name := range names {
+		v, err := c.Lookup(name)
+		if err != nil {
+			return nil, err
+		}
+		vars = append(vars, v)
+		size += v.Kind.Size()
+		// Storage type in the upper nibble, fetch-as type in the lower one.
+		req = append(req, v.Type<<4|v.Type, v.ID)
+	}
+	if size > crtp.MaxPayload-dataHeaderSize {
+		return nil, fmt.Errorf("Log block is too large: %d bytes, max: %d", size, crtp.MaxPayload-dataHeaderSize)
+	}
+
+	ch := make(chan Sample, sampleQueueSize)
+	b := &Block{Vars: vars, Period: period, C: ch, c: ch, client: c}
+	c.mu.Lock()
+	b.ID = c.nextID
+	c.nextID++
+	c.mu.Unlock()
+	req[1] = b.ID
+
+	// Block ids are not reused after New resets logging, so EEXIST means
+	// that the request has been retried after its reply was lost.
+	if err := c.control(req, b.ID); err != nil && err != EEXIST {
+		return nil, fmt.Errorf("Unable to create log block: %v", err)
+	}
+	c.mu.Lock()
+	c.blocks[b.ID] = b
+	c.mu.Unlock()
+	if err := c.control([]byte{CMD_START_LOGGING, b.ID, byte(period / periodUnit)}, b.ID); err != nil {
+		b.Stop()
+		return nil, fmt.Errorf("Unable to start logging: %v", err)
+	}
+	return b, nil
+}
+
+// Dropped returns the number of samples which have not been delivered
+// to C because it was full.
+func (b *Block) Dropped() int {
+	b.client.mu.Lock()
+	defer b.client.mu.Unlock()
+	return b.dropped
+}
+
+// Stop stops logging, deletes the block on Crazyflie and closes b.C.
+func (b *Block) Stop() error {
+	c := b.client
+	c.mu.Lock()
+	if b.stopped {
+		c.mu.Unlock()
+		return nil
+	}
+	b.stopped = true
+	delete(c.blocks, b.ID)
+	close(b.c)
+	c.mu.Unlock()
+
+	if err := c.control([]byte{CMD_STOP_LOGGING, b.ID}, b.ID); err != nil {
+		return fmt.Errorf("Unable to stop logging: %v", err)
+	}
+	// ENOENT means that a retried request has already deleted the block
+	if err := c.control([]byte{CMD_DELETE_BLOCK, b.ID}, b.ID); err != nil && err != ENOENT {
+		return fmt.Errorf("Unable to delete log block: %v", err)
+	}
+	return nil
+}
+
+// control sends a block control command and checks the error code in the reply.
+func (c *Client) control(req []byte, id uint8) error {
+	reply, err := c.conn.Request(crtp.NewPacket(crtp.PortLog, ControlChannel, req),
+		func(pk *crtp.Packet) bool {
+			if len(pk.Data) < 2 || pk.Data[0] != req[0] {
+				return false
+			}
+			return req[0] == CMD_RESET_LOGGING || pk.Data[1] == id
+		},
+		crtp.DefaultRequestTimeout, crtp.DefaultRequestRetries)
+	if err != nil {
+		return err
+	}
+	if len(reply.Data) >= 3 && reply.Data[2] != 0 {
+		return Errno(reply.Data[2])
+	}
+	return nil
+}
+
+func (c *Client) run(sub <-chan *crtp.Packet) {
+	for pk := range sub {
+		c.dispatch(pk, time.Now())
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for id, b := range c.blocks {
+		b.stopped = true
+		close(b.c)
+		delete(c.blocks, id)
+	}
+}
+
+func (c *Client) dispatch(pk *crtp.Packet, now time.Time) {
+	if len(pk.Data) < dataHeaderSize {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	b, ok := c.blocks[pk.Data[0]]
+	if !ok {
+		return
+	}
+	ms := int(pk.Data[1]) | int(pk.Data[2])<<8 | int(pk.Data[3])<<16
+	s := Sample{
+		Timestamp: time.Duration(ms) * time.Millisecond,
+		Received:  now,
+		Values:    make(map[string]interface{}),
+	}
+	p := pk.Data[dataHeaderSize:]
+	for _, v := range b.Vars {
+		val, err := v.Kind.Decode(p)
+		if err != nil {
+			// Truncated packet
+			return
+		}
+		s.Values[v.FullName()] = val
+		p = p[v.Kind.Size():]
+	}
+	// If the consumer is too slow, just drop the sample.
+	select {
+	case b.c <- s:
+	default:
+		b.dropped++
+	}
+}
+
+type byName []*Var
+
+func (s byName) Len() int           { return len(s) }
+func (s byName) Less(i, j int) bool { return s[i].FullName() < s[j].FullName() }
+func (s byName) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
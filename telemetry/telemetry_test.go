@@ -0,0 +1,141 @@
+package telemetry
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/samofly/cflie/crtp"
+	"github.com/samofly/cflie/crtp/crtptest"
+	"github.com/samofly/cflie/toc"
+)
+
+var testVars = []toc.Element{
+	{ID: 0, Group: "stabilizer", Name: "roll", Type: 7},
+	{ID: 1, Group: "stabilizer", Name: "thrust", Type: 2},
+}
+
+// fakeFlie serves log requests and sends a single sample for every started block.
+func fakeFlie() *crtptest.Flie {
+	return &crtptest.Flie{
+		TOC: map[crtp.Port]*toc.TOC{crtp.PortLog: toc.New(0x04030201, testVars, []byte{128, 16})},
+		Handle: func(pk *crtp.Packet) []*crtp.Packet {
+			if pk.Port != crtp.PortLog || pk.Channel != ControlChannel {
+				return nil
+			}
+			reply := []byte{pk.Data[0], 0, 0}
+			if len(pk.Data) > 1 {
+				reply[1] = pk.Data[1]
+			}
+			replies := []*crtp.Packet{crtp.NewPacket(pk.Port, pk.Channel, reply)}
+			if pk.Data[0] == CMD_START_LOGGING {
+				data := []byte{pk.Data[1], 0xE8, 0x03, 0x00, 0, 0, 0, 0, 0x30, 0x75}
+				binary.LittleEndian.PutUint32(data[4:], math.Float32bits(-1.5))
+				replies = append(replies, crtp.NewPacket(crtp.PortLog, DataChannel, data))
+			}
+			return replies
+		},
+	}
+}
+
+func TestBlock(t *testing.T) {
+	conn := fakeFlie().Conn()
+	defer conn.Close()
+
+	c, err := New(conn, nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if _, err = c.Start([]string{"stabilizer.pitch"}, 100*time.Millisecond); err == nil {
+		t.Errorf("Start must fail for unknown variables")
+	}
+	if _, err = c.Start([]string{"stabilizer.roll"}, time.Millisecond); err == nil {
+		t.Errorf("Start must fail for too short period")
+	}
+
+	b, err := c.Start([]string{"stabilizer.roll", "stabilizer.thrust"}, 100*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	select {
+	case s := <-b.C:
+		if s.Timestamp != time.Second {
+			t.Errorf("Unexpected timestamp: %v", s.Timestamp)
+		}
+		if s.Values["stabilizer.roll"] != float32(-1.5) || s.Values["stabilizer.thrust"] != uint16(30000) {
+			t.Errorf("Unexpected values: %v", s.Values)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Timed out waiting for a sample")
+	}
+	if err = b.Stop(); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	if _, ok := <-b.C; ok {
+		t.Errorf("Block channel must be closed after Stop")
+	}
+}
+
+func TestLostControlReply(t *testing.T) {
+	// Crazyflie which keeps track of blocks and loses the first reply to every command
+	blocks := make(map[uint8]bool)
+	replied := make(map[uint8]bool)
+	flie := fakeFlie()
+	handle := flie.Handle
+	flie.Handle = func(pk *crtp.Packet) []*crtp.Packet {
+		if pk.Channel != ControlChannel || len(pk.Data) < 2 {
+			return handle(pk)
+		}
+		cmd, id := pk.Data[0], pk.Data[1]
+		var errno Errno
+		switch {
+		case cmd == CMD_CREATE_BLOCK && blocks[id]:
+			errno = EEXIST
+		case cmd == CMD_CREATE_BLOCK:
+			blocks[id] = true
+		case cmd == CMD_DELETE_BLOCK && !blocks[id]:
+			errno = ENOENT
+		case cmd == CMD_DELETE_BLOCK:
+			delete(blocks, id)
+		}
+		if !replied[cmd] {
+			replied[cmd] = true
+			return nil
+		}
+		if errno != 0 {
+			return []*crtp.Packet{crtp.NewPacket(pk.Port, pk.Channel, []byte{cmd, id, byte(errno)})}
+		}
+		return handle(pk)
+	}
+	conn := flie.Conn()
+	defer conn.Close()
+
+	c, err := New(conn, nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	b, err := c.Start([]string{"stabilizer.roll"}, 100*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Start must succeed if the create block reply is lost, got: %v", err)
+	}
+	if err = b.Stop(); err != nil {
+		t.Fatalf("Stop must succeed if the delete block reply is lost, got: %v", err)
+	}
+	if len(blocks) != 0 {
+		t.Errorf("Log blocks are left on Crazyflie: %v", blocks)
+	}
+}
+
+func TestDroppedSamples(t *testing.T) {
+	c := &Client{blocks: make(map[uint8]*Block)}
+	ch := make(chan Sample, sampleQueueSize)
+	b := &Block{ID: 1, C: ch, c: ch, client: c}
+	c.blocks[b.ID] = b
+	for i := 0; i < sampleQueueSize+3; i++ {
+		c.dispatch(crtp.NewPacket(crtp.PortLog, DataChannel, []byte{1, 0, 0, 0}), time.Now())
+	}
+	if n := b.Dropped(); n != 3 {
+		t.Errorf("Dropped: want 3, got %d", n)
+	}
+}
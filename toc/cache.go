@@ -0,0 +1,83 @@
+package toc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/samofly/cflie/crtp"
+)
+
+// Cache stores downloaded TOCs keyed by port and firmware CRC.
+type Cache interface {
+	Load(port crtp.Port, crc uint32) (elems []Element, ok bool)
+	Store(port crtp.Port, crc uint32, elems []Element) error
+}
+
+type cacheKey struct {
+	port crtp.Port
+	crc  uint32
+}
+
+type memCache struct {
+	mu sync.Mutex
+	m  map[cacheKey][]Element
+}
+
+// NewMemCache returns a Cache that keeps TOCs in memory.
+func NewMemCache() Cache {
+	return &memCache{m: make(map[cacheKey][]Element)}
+}
+
+func (c *memCache) Load(port crtp.Port, crc uint32) (elems []Element, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elems, ok = c.m[cacheKey{port, crc}]
+	return
+}
+
+func (c *memCache) Store(port crtp.Port, crc uint32, elems []Element) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.m[cacheKey{port, crc}] = elems
+	return nil
+}
+
+type dirCache struct {
+	dir string
+}
+
+// NewDirCache returns a Cache that keeps TOCs as JSON files in dir.
+// The directory is created on the first Store.
+func NewDirCache(dir string) Cache {
+	return &dirCache{dir: dir}
+}
+
+func (c *dirCache) path(port crtp.Port, crc uint32) string {
+	return filepath.Join(c.dir, fmt.Sprintf("%d-%08X.json", port, crc))
+}
+
+func (c *dirCache) Load(port crtp.Port, crc uint32) (elems []Element, ok bool) {
+	data, err := ioutil.ReadFile(c.path(port, crc))
+	if err != nil {
+		return nil, false
+	}
+	if err = json.Unmarshal(data, &elems); err != nil {
+		return nil, false
+	}
+	return elems, true
+}
+
+func (c *dirCache) Store(port crtp.Port, crc uint32, elems []Element) error {
+	data, err := json.Marshal(elems)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(c.dir, 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(c.path(port, crc), data, 0644)
+}
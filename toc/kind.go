@@ -0,0 +1,296 @@
+package toc
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Kind is a wire representation of a variable value.
+// Param and log subsystems use different type codes which map to Kinds.
+type Kind uint8
+
+const (
+	Invalid Kind = iota
+	Int8
+	Int16
+	Int32
+	Int64
+	Uint8
+	Uint16
+	Uint32
+	Uint64
+	Float16
+	Float32
+	Float64
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Int8:
+		return "int8"
+	case Int16:
+		return "int16"
+	case Int32:
+		return "int32"
+	case Int64:
+		return "int64"
+	case Uint8:
+		return "uint8"
+	case Uint16:
+		return "uint16"
+	case Uint32:
+		return "uint32"
+	case Uint64:
+		return "uint64"
+	case Float16:
+		return "fp16"
+	case Float32:
+		return "float"
+	case Float64:
+		return "double"
+	}
+	return fmt.Sprintf("Kind:#%d", uint8(k))
+}
+
+// Size returns the number of bytes a value of this kind occupies on the wire.
+func (k Kind) Size() int {
+	switch k {
+	case Int8, Uint8:
+		return 1
+	case Int16, Uint16, Float16:
+		return 2
+	case Int32, Uint32, Float32:
+		return 4
+	case Int64, Uint64, Float64:
+		return 8
+	}
+	return 0
+}
+
+// Decode decodes a little-endian value from p. The result has the Go type
+// matching the kind (int8, uint16, float32, ...); Float16 is decoded as float32.
+func (k Kind) Decode(p []byte) (v interface{}, err error) {
+	if k.Size() == 0 {
+		return nil, fmt.Errorf("Unsupported kind: %s", k)
+	}
+	if len(p) < k.Size() {
+		return nil, fmt.Errorf("Not enough data to decode %s: %d bytes", k, len(p))
+	}
+	le := binary.LittleEndian
+	switch k {
+	case Int8:
+		return int8(p[0]), nil
+	case Int16:
+		return int16(le.Uint16(p)), nil
+	case Int32:
+		return int32(le.Uint32(p)), nil
+	case Int64:
+		return int64(le.Uint64(p)), nil
+	case Uint8:
+		return p[0], nil
+	case Uint16:
+		return le.Uint16(p), nil
+	case Uint32:
+		return le.Uint32(p), nil
+	case Uint64:
+		return le.Uint64(p), nil
+	case Float16:
+		return halfToFloat(le.Uint16(p)), nil
+	case Float32:
+		return math.Float32frombits(le.Uint32(p)), nil
+	case Float64:
+		return math.Float64frombits(le.Uint64(p)), nil
+	}
+	panic("unreachable")
+}
+
+// Encode converts v to the kind and encodes it as little-endian bytes.
+// v may be any Go integer or floating point value, or a string to be parsed.
+// Values which do not fit the kind, e.g. 300 for Uint8 or 1.5 for Int32, are rejected.
+func (k Kind) Encode(v interface{}) (p []byte, err error) {
+	if s, ok := v.(string); ok {
+		if v, err = k.Parse(s); err != nil {
+			return
+		}
+	}
+	var i int64
+	var u uint64
+	var f float64
+	var signed, unsigned bool
+	switch x := v.(type) {
+	case int:
+		i, signed = int64(x), true
+	case int8:
+		i, signed = int64(x), true
+	case int16:
+		i, signed = int64(x), true
+	case int32:
+		i, signed = int64(x), true
+	case int64:
+		i, signed = x, true
+	case uint:
+		u, unsigned = uint64(x), true
+	case uint8:
+		u, unsigned = uint64(x), true
+	case uint16:
+		u, unsigned = uint64(x), true
+	case uint32:
+		u, unsigned = uint64(x), true
+	case uint64:
+		u, unsigned = x, true
+	case float32:
+		f = float64(x)
+	case float64:
+		f = x
+	default:
+		return nil, fmt.Errorf("Unsupported value type: %T", v)
+	}
+	if k.Size() == 0 {
+		return nil, fmt.Errorf("Unsupported kind: %s", k)
+	}
+	p = make([]byte, k.Size())
+	le := binary.LittleEndian
+	switch k {
+	case Float16, Float32, Float64:
+		if signed {
+			f = float64(i)
+		} else if unsigned {
+			f = float64(u)
+		}
+		var inf bool
+		switch k {
+		case Float16:
+			h := floatToHalf(float32(f))
+			le.PutUint16(p, h)
+			inf = h&0x7FFF == 0x7C00
+		case Float32:
+			le.PutUint32(p, math.Float32bits(float32(f)))
+			inf = math.IsInf(float64(float32(f)), 0)
+		case Float64:
+			le.PutUint64(p, math.Float64bits(f))
+		}
+		if inf && !math.IsInf(f, 0) {
+			return nil, fmt.Errorf("Value %v is out of range of %s", v, k)
+		}
+		return
+	}
+
+	min, max := k.intRange()
+	var ok bool
+	switch {
+	case signed:
+		ok = i >= min && (i < 0 || uint64(i) <= max)
+		u = uint64(i)
+	case unsigned:
+		ok = u <= max
+	default:
+		// float64(max)+1 is exact, unlike float64(max) for Int64 and Uint64
+		ok = f == math.Trunc(f) && f >= float64(min) && f < float64(max)+1
+		if ok && f < 0 {
+			u = uint64(int64(f))
+		} else if ok {
+			u = uint64(f)
+		}
+	}
+	if !ok {
+		return nil, fmt.Errorf("Value %v is out of range of %s", v, k)
+	}
+	// Two's complement of signed values is truncated the same way as unsigned ones
+	switch k.Size() {
+	case 1:
+		p[0] = byte(u)
+	case 2:
+		le.PutUint16(p, uint16(u))
+	case 4:
+		le.PutUint32(p, uint32(u))
+	case 8:
+		le.PutUint64(p, u)
+	}
+	return
+}
+
+// intRange returns the range of values of an integer kind.
+func (k Kind) intRange() (min int64, max uint64) {
+	bits := uint(k.Size() * 8)
+	switch k {
+	case Int8, Int16, Int32, Int64:
+		return -1 << (bits - 1), 1<<(bits-1) - 1
+	}
+	return 0, 1<<bits - 1
+}
+
+// Parse parses a string representation of a value of this kind.
+func (k Kind) Parse(s string) (v interface{}, err error) {
+	switch k {
+	case Int8, Int16, Int32, Int64:
+		return strconv.ParseInt(s, 0, k.Size()*8)
+	case Uint8, Uint16, Uint32, Uint64:
+		return strconv.ParseUint(s, 0, k.Size()*8)
+	case Float16, Float32:
+		return strconv.ParseFloat(s, 32)
+	case Float64:
+		return strconv.ParseFloat(s, 64)
+	}
+	return nil, fmt.Errorf("Unsupported kind: %s", k)
+}
+
+// halfToFloat converts IEEE 754 half precision number to float32.
+func halfToFloat(h uint16) float32 {
+	sign := uint32(h>>15) << 31
+	exp := uint32(h>>10) & 0x1F
+	mant := uint32(h) & 0x3FF
+	switch {
+	case exp == 0 && mant == 0:
+		return math.Float32frombits(sign)
+	case exp == 0:
+		// Subnormal
+		f := float32(mant) / (1 << 24)
+		if sign != 0 {
+			f = -f
+		}
+		return f
+	case exp == 0x1F:
+		return math.Float32frombits(sign | 0xFF<<23 | mant<<13)
+	}
+	return math.Float32frombits(sign | (exp+127-15)<<23 | mant<<13)
+}
+
+// floatToHalf converts float32 to IEEE 754 half precision number, rounding to nearest even.
+// Values beyond the half precision range become infinities.
+func floatToHalf(f float32) uint16 {
+	b := math.Float32bits(f)
+	sign := uint16(b>>16) & 0x8000
+	exp := int(b>>23&0xFF) - 127 + 15
+	mant := b & 0x7FFFFF
+	switch {
+	case b&0x7FFFFFFF == 0:
+		return sign
+	case b>>23&0xFF == 0xFF:
+		if mant != 0 {
+			return sign | 0x7E00
+		}
+		return sign | 0x7C00
+	case exp >= 0x1F:
+		return sign | 0x7C00
+	case exp <= 0:
+		// Subnormal; anything below half of the smallest one rounds to zero
+		if exp < -10 {
+			return sign
+		}
+		return sign | uint16(roundShift(mant|0x800000, uint(14-exp)))
+	}
+	// Rounding up may carry into the exponent, up to the infinity
+	return sign | uint16(roundShift(uint32(exp)<<23|mant, 13))
+}
+
+// roundShift shifts x right by n bits, rounding to nearest even.
+func roundShift(x uint32, n uint) uint32 {
+	r := x >> n
+	rem, half := x&(1<<n-1), uint32(1)<<(n-1)
+	if rem > half || rem == half && r&1 != 0 {
+		r++
+	}
+	return r
+}
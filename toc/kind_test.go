@@ -0,0 +1,163 @@
+package toc
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestKindRoundTrip(t *testing.T) {
+	tests := []struct {
+		kind Kind
+		in   interface{}
+		want interface{}
+		wire []byte
+	}{
+		{Int8, -128, int8(-128), []byte{0x80}},
+		{Int8, "127", int8(127), []byte{0x7F}},
+		{Int16, int16(-2), int16(-2), []byte{0xFE, 0xFF}},
+		{Int32, uint8(200), int32(200), []byte{200, 0, 0, 0}},
+		{Int64, int64(math.MinInt64), int64(math.MinInt64), []byte{0, 0, 0, 0, 0, 0, 0, 0x80}},
+		{Uint8, 255, uint8(255), []byte{0xFF}},
+		{Uint16, "0x1234", uint16(0x1234), []byte{0x34, 0x12}},
+		{Uint32, float64(7), uint32(7), []byte{7, 0, 0, 0}},
+		{Uint64, uint64(math.MaxUint64), uint64(math.MaxUint64), []byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}},
+		{Float16, 1, float32(1), []byte{0x00, 0x3C}},
+		{Float16, "-2.5", float32(-2.5), []byte{0x00, 0xC1}},
+		{Float32, float32(0.1), float32(0.1), []byte{0xCD, 0xCC, 0xCC, 0x3D}},
+		{Float32, 250, float32(250), []byte{0, 0, 0x7A, 0x43}},
+		{Float64, "-2", float64(-2), []byte{0, 0, 0, 0, 0, 0, 0, 0xC0}},
+	}
+	for _, tt := range tests {
+		p, err := tt.kind.Encode(tt.in)
+		if err != nil {
+			t.Errorf("%s.Encode(%T(%v)): %v", tt.kind, tt.in, tt.in, err)
+			continue
+		}
+		if fmt.Sprint(p) != fmt.Sprint(tt.wire) {
+			t.Errorf("%s.Encode(%T(%v)): want %v, got %v", tt.kind, tt.in, tt.in, tt.wire, p)
+		}
+		v, err := tt.kind.Decode(p)
+		if err != nil {
+			t.Errorf("%s.Decode(%v): %v", tt.kind, p, err)
+			continue
+		}
+		if v != tt.want {
+			t.Errorf("%s.Decode(%v): want %T(%v), got %T(%v)", tt.kind, p, tt.want, tt.want, v, v)
+		}
+	}
+}
+
+func TestKindEncodeRange(t *testing.T) {
+	tests := []struct {
+		kind Kind
+		in   interface{}
+	}{
+		{Uint8, 300},
+		{Uint8, "300"},
+		{Uint8, -1},
+		{Int8, 128},
+		{Int8, int64(-129)},
+		{Int16, uint64(math.MaxUint64)},
+		{Uint32, uint64(1 << 32)},
+		{Int32, 1.5},
+		{Int32, "1.5"},
+		{Uint64, -1.0},
+		{Int64, float64(1 << 63)},
+		{Uint64, math.Inf(1)},
+		{Int8, math.NaN()},
+		{Float16, 65520},
+		{Float16, "-1e5"},
+		{Float32, 1e39},
+		{Invalid, 0},
+		{Int8, true},
+	}
+	for _, tt := range tests {
+		if p, err := tt.kind.Encode(tt.in); err == nil {
+			t.Errorf("%s.Encode(%T(%v)) must fail, got %v", tt.kind, tt.in, tt.in, p)
+		}
+	}
+	// Infinities are valid floating point values
+	if _, err := Float16.Encode(math.Inf(-1)); err != nil {
+		t.Errorf("Float16.Encode(-Inf): %v", err)
+	}
+}
+
+func TestHalf(t *testing.T) {
+	tests := []struct {
+		h uint16
+		f float32
+	}{
+		{0x0000, 0},
+		{0x3C00, 1},
+		{0xC000, -2},
+		{0x3555, 0.333251953125},
+		{0x7BFF, 65504},
+		{0x0400, 1.0 / (1 << 14)},
+		// Subnormals
+		{0x0001, 1.0 / (1 << 24)},
+		{0x83FF, -1023.0 / (1 << 24)},
+		{0x7C00, float32(math.Inf(1))},
+		{0xFC00, float32(math.Inf(-1))},
+	}
+	for _, tt := range tests {
+		if f := halfToFloat(tt.h); f != tt.f {
+			t.Errorf("halfToFloat(0x%04X): want %v, got %v", tt.h, tt.f, f)
+		}
+		if h := floatToHalf(tt.f); h != tt.h {
+			t.Errorf("floatToHalf(%v): want 0x%04X, got 0x%04X", tt.f, tt.h, h)
+		}
+	}
+	if h := floatToHalf(float32(math.Copysign(0, -1))); h != 0x8000 {
+		t.Errorf("floatToHalf(-0): want 0x8000, got 0x%04X", h)
+	}
+	if f := halfToFloat(0x7E00); !math.IsNaN(float64(f)) {
+		t.Errorf("halfToFloat(0x7E00): want NaN, got %v", f)
+	}
+	if h := floatToHalf(float32(math.NaN())); h&0x7C00 != 0x7C00 || h&0x3FF == 0 {
+		t.Errorf("floatToHalf(NaN): want NaN, got 0x%04X", h)
+	}
+
+	// Every half precision number survives the round trip
+	for h := 0; h <= 0xFFFF; h++ {
+		if h&0x7C00 == 0x7C00 && h&0x3FF != 0 {
+			continue
+		}
+		if got := floatToHalf(halfToFloat(uint16(h))); got != uint16(h) {
+			t.Fatalf("floatToHalf(halfToFloat(0x%04X)) = 0x%04X", h, got)
+		}
+	}
+}
+
+func TestHalfRounding(t *testing.T) {
+	const ulp = 1.0 / (1 << 10)
+	tests := []struct {
+		f float32
+		h uint16
+	}{
+		// Ties go to even
+		{1 + ulp/2, 0x3C00},
+		{1 + 3*ulp/2, 0x3C02},
+		{1 + ulp/2 + ulp/1024, 0x3C01},
+		{1 + ulp/2 - ulp/1024, 0x3C00},
+		{-(1 + 3*ulp/2), 0xBC02},
+		// Overflow
+		{65519, 0x7BFF},
+		{65520, 0x7C00},
+		{1e10, 0x7C00},
+		{-1e10, 0xFC00},
+		// Underflow into subnormals and zero
+		{1.5 / (1 << 24), 0x0002},
+		{1.0 / (1 << 25), 0x0000},
+		{1.5 / (1 << 25), 0x0001},
+		{1e-10, 0x0000},
+		{-1e-10, 0x8000},
+		// The largest subnormal rounds up to the smallest normal number
+		{1023.75 / (1 << 24), 0x0400},
+	}
+	for _, tt := range tests {
+		if h := floatToHalf(tt.f); h != tt.h {
+			t.Errorf("floatToHalf(%v): want 0x%04X, got 0x%04X", tt.f, tt.h, h)
+		}
+	}
+}
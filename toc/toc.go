@@ -0,0 +1,137 @@
+// Package toc downloads the table of contents (TOC) of Crazyflie variables.
+//
+// Param and log subsystems describe their variables with the same protocol
+// on channel 0 of their CRTP port:
+//
+//	GetItem: host sends [0, id], Crazyflie replies [0, id, type, group\0name\0]
+//	GetInfo: host sends [1], Crazyflie replies [1, count, crc32 (LE), ...]
+//
+// The CRC identifies the firmware build, so a downloaded TOC can be cached.
+package toc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sort"
+
+	"github.com/samofly/cflie/crtp"
+)
+
+const (
+	Channel = 0
+
+	CMD_GET_ITEM = 0
+	CMD_GET_INFO = 1
+)
+
+// Element describes a single variable. Type is a subsystem specific type code.
+type Element struct {
+	ID    uint8
+	Group string
+	Name  string
+	Type  uint8
+}
+
+// FullName returns the name of the variable in "group.name" form.
+func (e Element) FullName() string {
+	return e.Group + "." + e.Name
+}
+
+type TOC struct {
+	CRC      uint32
+	Elements []Element
+	// Extra info bytes which follow count and CRC in GetInfo reply.
+	Info   []byte
+	byName map[string]int
+}
+
+// New builds a TOC from the list of elements.
+func New(crc uint32, elems []Element, info []byte) *TOC {
+	t := &TOC{CRC: crc, Elements: elems, Info: info, byName: make(map[string]int)}
+	sort.Sort(byID(t.Elements))
+	for i, e := range t.Elements {
+		t.byName[e.FullName()] = i
+	}
+	return t
+}
+
+// Find looks up an element by its "group.name".
+func (t *TOC) Find(name string) (e Element, ok bool) {
+	i, ok := t.byName[name]
+	if !ok {
+		return
+	}
+	return t.Elements[i], true
+}
+
+// ByID looks up an element by its id.
+func (t *TOC) ByID(id uint8) (e Element, ok bool) {
+	i := sort.Search(len(t.Elements), func(i int) bool { return t.Elements[i].ID >= id })
+	if i < len(t.Elements) && t.Elements[i].ID == id {
+		return t.Elements[i], true
+	}
+	return
+}
+
+type byID []Element
+
+func (s byID) Len() int           { return len(s) }
+func (s byID) Less(i, j int) bool { return s[i].ID < s[j].ID }
+func (s byID) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// Fetch downloads the TOC of the specified port. If cache is not nil,
+// the TOC is looked up there by the firmware CRC first and stored there after download.
+func Fetch(conn *crtp.Conn, port crtp.Port, cache Cache) (*TOC, error) {
+	reply, err := conn.Request(crtp.NewPacket(port, Channel, []byte{CMD_GET_INFO}),
+		func(pk *crtp.Packet) bool { return len(pk.Data) >= 6 && pk.Data[0] == CMD_GET_INFO },
+		crtp.DefaultRequestTimeout, crtp.DefaultRequestRetries)
+	if err != nil {
+		return nil, fmt.Errorf("%s TOC info: %v", port, err)
+	}
+	count := int(reply.Data[1])
+	crc := binary.LittleEndian.Uint32(reply.Data[2:6])
+	info := append([]byte(nil), reply.Data[6:]...)
+
+	if cache != nil {
+		if elems, ok := cache.Load(port, crc); ok && len(elems) == count {
+			return New(crc, elems, info), nil
+		}
+	}
+
+	elems := make([]Element, 0, count)
+	for id := 0; id < count; id++ {
+		e, err := fetchElement(conn, port, uint8(id))
+		if err != nil {
+			return nil, err
+		}
+		elems = append(elems, e)
+	}
+	if cache != nil {
+		if err = cache.Store(port, crc, elems); err != nil {
+			return nil, fmt.Errorf("Unable to cache %s TOC: %v", port, err)
+		}
+	}
+	return New(crc, elems, info), nil
+}
+
+func fetchElement(conn *crtp.Conn, port crtp.Port, id uint8) (e Element, err error) {
+	reply, err := conn.Request(crtp.NewPacket(port, Channel, []byte{CMD_GET_ITEM, id}),
+		func(pk *crtp.Packet) bool {
+			return len(pk.Data) >= 3 && pk.Data[0] == CMD_GET_ITEM && pk.Data[1] == id
+		},
+		crtp.DefaultRequestTimeout, crtp.DefaultRequestRetries)
+	if err != nil {
+		return e, fmt.Errorf("%s TOC element #%d: %v", port, id, err)
+	}
+	return parseElement(reply.Data[1:])
+}
+
+// parseElement parses [id, type, group\0name\0].
+func parseElement(p []byte) (e Element, err error) {
+	parts := bytes.Split(p[2:], []byte{0})
+	if len(parts) < 2 {
+		return e, fmt.Errorf("Malformed TOC element: %v", p)
+	}
+	return Element{ID: p[0], Type: p[1], Group: string(parts[0]), Name: string(parts[1])}, nil
+}
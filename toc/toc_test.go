@@ -0,0 +1,91 @@
+package toc_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/samofly/cflie/crtp"
+	"github.com/samofly/cflie/crtp/crtptest"
+	"github.com/samofly/cflie/toc"
+)
+
+var testElements = []toc.Element{
+	{ID: 0, Group: "pid_rate", Name: "roll_kp", Type: 0x06},
+	{ID: 1, Group: "imu_sensors", Name: "HMC5883L", Type: 0x48},
+	{ID: 2, Group: "flightctrl", Name: "xmode", Type: 0x08},
+}
+
+func fetch(t *testing.T, elems []toc.Element, cache toc.Cache) *toc.TOC {
+	t.Helper()
+	f := &crtptest.Flie{TOC: map[crtp.Port]*toc.TOC{crtp.PortParam: toc.New(0xDEADBEEF, elems, []byte{42})}}
+	conn := f.Conn()
+	defer conn.Close()
+	res, err := toc.Fetch(conn, crtp.PortParam, cache)
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	return res
+}
+
+func TestFetch(t *testing.T) {
+	res := fetch(t, testElements, nil)
+	if res.CRC != 0xDEADBEEF {
+		t.Errorf("Unexpected CRC: 0x%08X", res.CRC)
+	}
+	if !reflect.DeepEqual(res.Elements, testElements) {
+		t.Errorf("Unexpected elements. Want: %+v, got: %+v", testElements, res.Elements)
+	}
+	if !reflect.DeepEqual(res.Info, []byte{42}) {
+		t.Errorf("Unexpected info: %v", res.Info)
+	}
+	if e, ok := res.Find("flightctrl.xmode"); !ok || e.ID != 2 {
+		t.Errorf("Find(flightctrl.xmode): %+v, %v", e, ok)
+	}
+	if _, ok := res.Find("flightctrl.ymode"); ok {
+		t.Errorf("Find must fail for unknown variables")
+	}
+	if e, ok := res.ByID(1); !ok || e.FullName() != "imu_sensors.HMC5883L" {
+		t.Errorf("ByID(1): %+v, %v", e, ok)
+	}
+	if _, ok := res.ByID(3); ok {
+		t.Errorf("ByID must fail for unknown ids")
+	}
+}
+
+func testCache(t *testing.T, cache toc.Cache) {
+	if _, ok := cache.Load(crtp.PortParam, 0xDEADBEEF); ok {
+		t.Errorf("Empty cache must not have TOCs")
+	}
+	fetch(t, testElements, cache)
+	elems, ok := cache.Load(crtp.PortParam, 0xDEADBEEF)
+	if !ok || !reflect.DeepEqual(elems, testElements) {
+		t.Errorf("TOC is not cached: %+v", elems)
+	}
+	if _, ok := cache.Load(crtp.PortLog, 0xDEADBEEF); ok {
+		t.Errorf("TOCs must be cached per port")
+	}
+
+	// Crazyflie with the same CRC is not asked for the elements
+	renamed := append([]toc.Element(nil), testElements...)
+	renamed[0].Name = "pitch_kp"
+	if res := fetch(t, renamed, cache); !reflect.DeepEqual(res.Elements, testElements) {
+		t.Errorf("Cached TOC is not used: %+v", res.Elements)
+	}
+	// Cached TOC with a different number of elements is stale
+	if res := fetch(t, renamed[:2], cache); !reflect.DeepEqual(res.Elements, renamed[:2]) {
+		t.Errorf("Stale TOC is used: %+v", res.Elements)
+	}
+}
+
+func TestMemCache(t *testing.T) {
+	testCache(t, toc.NewMemCache())
+}
+
+func TestDirCache(t *testing.T) {
+	dir := t.TempDir() + "/toc"
+	testCache(t, toc.NewDirCache(dir))
+	// TOCs are kept across restarts
+	if elems, ok := toc.NewDirCache(dir).Load(crtp.PortParam, 0xDEADBEEF); !ok || len(elems) != 2 {
+		t.Errorf("TOC is not stored in %s: %+v", dir, elems)
+	}
+}
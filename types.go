@@ -1,6 +1,10 @@
 package cflie
 
-import "fmt"
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
 
 type DataRate uint16
 
@@ -24,7 +28,7 @@ const (
 	MaxChannel = 125
 )
 
-var Rates = []DataRate{DATA_RATE_250K} // Temporary disable, DATA_RATE_1M, DATA_RATE_2M}
+var Rates = []DataRate{DATA_RATE_250K, DATA_RATE_1M, DATA_RATE_2M}
 
 type DeviceInfo interface {
 	Bus() int
@@ -44,17 +48,50 @@ type Device interface {
 	SetRadioAddress(addr [5]byte) error
 }
 
+// DefaultRadioAddress is the factory pipe address of Crazyflie.
+var DefaultRadioAddress = [5]byte{0xE7, 0xE7, 0xE7, 0xE7, 0xE7}
+
+// RadioAddr returns a radio URI like radio://0/10/250K.
 func RadioAddr(rate DataRate, ch uint8) string {
 	return fmt.Sprintf("radio://0/%d/%s", ch, rate)
 }
 
+// RadioURI returns a radio URI with a pipe address, like radio://0/80/2M/E7E7E7E701.
+// The address is omitted if it's DefaultRadioAddress.
+func RadioURI(rate DataRate, ch uint8, addr [5]byte) string {
+	if addr == DefaultRadioAddress {
+		return RadioAddr(rate, ch)
+	}
+	return fmt.Sprintf("%s/%X", RadioAddr(rate, ch), addr[:])
+}
+
+// ParseAddr parses a radio URI and returns its rate and channel.
+// See ParseURI for URIs with a pipe address.
 func ParseAddr(addr string) (rate DataRate, ch uint8, err error) {
-	var label string
-	_, err = fmt.Sscanf(addr, "radio://0/%d/%s", &ch, &label)
-	if err != nil {
-		return 0, 0, err
+	rate, ch, _, err = ParseURI(addr)
+	return
+}
+
+// ParseURI parses a radio URI like radio://0/80/2M or radio://0/80/2M/E7E7E7E7E7.
+// If the pipe address is omitted, DefaultRadioAddress is returned.
+func ParseURI(uri string) (rate DataRate, ch uint8, addr [5]byte, err error) {
+	parts := strings.Split(uri, "/")
+	// "radio:", "", "0", channel, rate[, address]
+	if len(parts) < 5 || len(parts) > 6 || parts[0] != "radio:" || parts[1] != "" || parts[2] != "0" {
+		err = fmt.Errorf("Malformed radio URI: %s", uri)
+		return
+	}
+	var chNum int
+	if _, err = fmt.Sscanf(parts[3], "%d", &chNum); err != nil {
+		err = fmt.Errorf("Malformed channel in %s: %v", uri, err)
+		return
 	}
-	switch label {
+	if chNum < 0 || chNum > MaxChannel {
+		err = fmt.Errorf("Channel is out of range: %d, max: %d", chNum, MaxChannel)
+		return
+	}
+	ch = uint8(chNum)
+	switch parts[4] {
 	case "250K":
 		rate = DATA_RATE_250K
 	case "1M":
@@ -62,7 +99,24 @@ func ParseAddr(addr string) (rate DataRate, ch uint8, err error) {
 	case "2M":
 		rate = DATA_RATE_2M
 	default:
-		return 0, 0, fmt.Errorf("Unknown rate: %s", label)
+		err = fmt.Errorf("Unknown rate: %s", parts[4])
+		return
+	}
+	addr = DefaultRadioAddress
+	if len(parts) == 6 {
+		if addr, err = ParseRadioAddress(parts[5]); err != nil {
+			return 0, 0, addr, err
+		}
 	}
 	return
 }
+
+// ParseRadioAddress parses a pipe address written as 10 hex digits, like E7E7E7E7E7.
+func ParseRadioAddress(s string) (addr [5]byte, err error) {
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != len(addr) {
+		return addr, fmt.Errorf("Radio address must be 10 hex digits, got: %s", s)
+	}
+	copy(addr[:], b)
+	return addr, nil
+}
@@ -0,0 +1,43 @@
+package cflie
+
+import "testing"
+
+func TestParseURI(t *testing.T) {
+	tests := []struct {
+		uri       string
+		rate      DataRate
+		ch        uint8
+		radioAddr [5]byte
+	}{
+		{"radio://0/10/250K", DATA_RATE_250K, 10, DefaultRadioAddress},
+		{"radio://0/80/2M/E7E7E7E701", DATA_RATE_2M, 80, [5]byte{0xE7, 0xE7, 0xE7, 0xE7, 0x01}},
+		{"radio://0/125/1M", DATA_RATE_1M, 125, DefaultRadioAddress},
+	}
+	for _, tt := range tests {
+		rate, ch, radioAddr, err := ParseURI(tt.uri)
+		if err != nil {
+			t.Errorf("ParseURI(%s): %v", tt.uri, err)
+			continue
+		}
+		if rate != tt.rate || ch != tt.ch || radioAddr != tt.radioAddr {
+			t.Errorf("ParseURI(%s): want %s/%d/%X, got %s/%d/%X", tt.uri,
+				tt.rate, tt.ch, tt.radioAddr, rate, ch, radioAddr)
+		}
+		if uri := RadioURI(rate, ch, radioAddr); uri != tt.uri {
+			t.Errorf("RadioURI: want %s, got %s", tt.uri, uri)
+		}
+	}
+
+	for _, uri := range []string{
+		"radio://0/10",
+		"radio://0/126/250K",
+		"radio://0/10/3M",
+		"radio://0/10/250K/E7E7",
+		"radio://0/10/250K/E7E7E7E7ZZ",
+		"usb://0/10/250K",
+	} {
+		if _, _, _, err := ParseURI(uri); err == nil {
+			t.Errorf("ParseURI(%s) must fail", uri)
+		}
+	}
+}
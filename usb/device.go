@@ -31,7 +31,7 @@ const (
 	DefaultDataRate = cflie.DATA_RATE_250K
 )
 
-var DefaultRadioAddress = [5]byte{0xE7, 0xE7, 0xE7, 0xE7, 0xE7}
+var DefaultRadioAddress = cflie.DefaultRadioAddress
 
 var defaultContext = usb.NewContext()
 
@@ -170,6 +170,19 @@ func (d *device) SetRadioAddress(addr [5]byte) error {
 	return d.control(SET_RADIO_ADDRESS, 0, addr[:])
 }
 
+func (d *device) SetRadioConfig(cfg cflie.RadioConfig) (err error) {
+	if err = cfg.Validate(); err != nil {
+		return
+	}
+	if err = d.control(SET_RADIO_POWER, uint16(cfg.Power), nil); err != nil {
+		return
+	}
+	if err = d.control(SET_RADIO_ARD, uint16(cfg.ARD), nil); err != nil {
+		return
+	}
+	return d.control(SET_RADIO_ARC, uint16(cfg.ARC), nil)
+}
+
 func (d *device) initDongle(ch uint8, rate cflie.DataRate) (err error) {
 	d.d.ReadTimeout = 50 * time.Millisecond
 	d.d.ControlTimeout = 10 * time.Second // Scans are slow
@@ -204,16 +217,7 @@ func (d *device) initDongle(ch uint8, rate cflie.DataRate) (err error) {
 	if err = d.SetRadioAddress(DefaultRadioAddress); err != nil {
 		return
 	}
-	if err = d.control(SET_RADIO_POWER, RADIO_POWER_0dBm, nil); err != nil {
-		return
-	}
-	if err = d.control(SET_RADIO_ARC, 3, nil); err != nil {
-		return
-	}
-	if err = d.control(SET_RADIO_ARD, 0x80|32, nil); err != nil {
-		return
-	}
-	if err = d.control(SET_RADIO_ARC, 10, nil); err != nil {
+	if err = d.SetRadioConfig(cflie.DefaultRadioConfig); err != nil {
 		return
 	}
 	if err = d.setChannel(ch); err != nil {
@@ -1,35 +1,53 @@
 package usb
 
 import (
-	"log"
+	"log/slog"
 	"time"
 
 	"github.com/samofly/cflie"
 )
 
-var Hub = &hub{}
+// Hub tracks CrazyRadio dongles and logs to slog.Default().
+var Hub = NewHub(nil)
 
-type hub struct{}
+// NewHub returns a Hub which logs to logger. If logger is nil, slog.Default() is used.
+func NewHub(logger cflie.Logger) cflie.IntervalHub {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &hub{log: logger}
+}
+
+// DefaultListInterval is how often the hub lists dongles, unless the station asks otherwise.
+const DefaultListInterval = time.Second
+
+type hub struct {
+	log cflie.Logger
+}
 
 func (h *hub) ListPush(cancelChan <-chan bool, errChan chan<- error) <-chan []cflie.DeviceInfo {
+	return h.ListPushEvery(DefaultListInterval, cancelChan, errChan)
+}
+
+func (h *hub) ListPushEvery(interval time.Duration, cancelChan <-chan bool, errChan chan<- error) <-chan []cflie.DeviceInfo {
 	lsChan := make(chan []cflie.DeviceInfo)
-	go listPush(lsChan, cancelChan, errChan)
+	go h.listPush(interval, lsChan, cancelChan, errChan)
 	return lsChan
 }
 
-func listPush(lsChan chan<- []cflie.DeviceInfo, cancelChan <-chan bool, errChan chan<- error) {
+func (h *hub) listPush(interval time.Duration, lsChan chan<- []cflie.DeviceInfo, cancelChan <-chan bool, errChan chan<- error) {
 	defer close(errChan)
 	defer close(lsChan)
 	first := true
 	for {
 		if !first {
-			log.Printf("Let's sleep")
+			h.log.Debug("Sleeping before listing dongles")
 			select {
 			case <-cancelChan:
 				return
-			case <-time.After(time.Second):
+			case <-time.After(interval):
 			}
-			log.Printf("Wake up!")
+			h.log.Debug("Listing dongles")
 		}
 		first = false
 		list, err := ListDevices()
@@ -37,13 +55,13 @@ func listPush(lsChan chan<- []cflie.DeviceInfo, cancelChan <-chan bool, errChan
 			errChan <- err
 			continue
 		}
-		log.Printf("Let's report the results")
+		h.log.Debug("Reporting dongles", "count", len(list))
 		select {
 		case <-cancelChan:
 			return
 		case lsChan <- list:
 		}
-		log.Printf("results reported")
+		h.log.Debug("Dongles reported")
 	}
 }
 
@@ -2,9 +2,12 @@ package usb
 
 import (
 	"fmt"
+	"sync"
+	"time"
+	"unicode/utf16"
 
-	"github.com/samofly/cflie"
 	"github.com/kylelemons/gousb/usb"
+	"github.com/samofly/cflie"
 )
 
 const (
@@ -12,29 +15,167 @@ const (
 	Product = 0x7777
 )
 
+// serialRetryDelay is how long to wait before reading the serial number
+// of a dongle again after a failed attempt.
+const serialRetryDelay = 30 * time.Second
+
+// lister lists CrazyRadio dongles and caches their serial numbers,
+// so that a dongle is only opened to read its serial number once it's plugged in.
+type lister struct {
+	// list enumerates the attached dongles without opening them
+	list func() ([]usb.Descriptor, error)
+	// serial opens the dongle and reads its serial number
+	serial func(desc usb.Descriptor) (string, error)
+	retry  time.Duration
+
+	mu sync.Mutex
+	// Serial numbers by DeviceInfo.String()
+	cache map[string]serialEntry
+}
+
+type serialEntry struct {
+	serial string
+	// Set if the serial number could not be read, so that it's read again after the retry delay
+	failedAt time.Time
+}
+
+var defaultLister = &lister{
+	list:   listDescriptors,
+	serial: readSerialOf,
+	retry:  serialRetryDelay,
+	cache:  make(map[string]serialEntry),
+}
+
 // ListDevices returns the list of attached CrazyRadio devices.
 func ListDevices() ([]cflie.DeviceInfo, error) {
+	return defaultLister.ListDevices()
+}
+
+func (l *lister) ListDevices() ([]cflie.DeviceInfo, error) {
+	descs, err := l.list()
+	if err != nil {
+		return nil, err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
 	var d []cflie.DeviceInfo
-	_, err := defaultContext.ListDevices(func(desc *usb.Descriptor) bool {
+	found := make(map[string]bool)
+	for _, desc := range descs {
+		info := deviceInfo{desc: desc}
+		key := info.String()
+		found[key] = true
+		e, ok := l.cache[key]
+		if !ok || !e.failedAt.IsZero() && time.Since(e.failedAt) >= l.retry {
+			// A dongle which can't be opened, e.g. for lack of permissions, is still listed.
+			// Old firmware does not report serial numbers; the dongle is still usable.
+			e = serialEntry{}
+			if e.serial, err = l.serial(desc); err != nil {
+				e.failedAt = time.Now()
+			}
+			l.cache[key] = e
+		}
+		info.serial = e.serial
+		d = append(d, info)
+	}
+	for key := range l.cache {
+		if !found[key] {
+			delete(l.cache, key)
+		}
+	}
+	return d, nil
+}
+
+// listDescriptors enumerates the attached dongles without opening them.
+func listDescriptors() (list []usb.Descriptor, err error) {
+	_, err = defaultContext.ListDevices(func(desc *usb.Descriptor) bool {
 		if desc.Vendor == Vendor && desc.Product == Product {
-			d = append(d, deviceInfo{*desc})
+			list = append(list, *desc)
 		}
 		return false
 	})
+	return
+}
+
+// readSerialOf opens the dongle just long enough to read its serial number.
+func readSerialOf(desc usb.Descriptor) (string, error) {
+	devs, err := defaultContext.ListDevices(func(d *usb.Descriptor) bool {
+		return d.Vendor == desc.Vendor && d.Product == desc.Product &&
+			d.Bus == desc.Bus && d.Address == desc.Address
+	})
+	for _, dev := range devs {
+		defer dev.Close()
+	}
 	if err != nil {
-		return nil, err
+		return "", err
 	}
-	return d, nil
+	if len(devs) == 0 {
+		return "", ErrDeviceNotFound
+	}
+	if len(devs) > 1 {
+		return "", ErrTooManyDevicesMatch
+	}
+	return readSerial(devs[0])
+}
+
+// Standard USB requests to read the serial number
+const (
+	requestGetDescriptor    = 0x06
+	descriptorTypeDevice    = 0x01
+	descriptorTypeString    = 0x03
+	requestTypeStandardIn   = 0x80
+	langIDEnglishUS         = 0x0409
+	deviceDescriptorSize    = 18
+	iSerialNumberOffset     = 16
+	maxStringDescriptorSize = 255
+)
+
+// readSerial reads the serial number string descriptor of the dongle.
+// It returns an empty string if the dongle has no serial number.
+func readSerial(dev *usb.Device) (string, error) {
+	desc := make([]byte, deviceDescriptorSize)
+	n, err := dev.Control(requestTypeStandardIn, requestGetDescriptor, descriptorTypeDevice<<8, 0, desc)
+	if err != nil {
+		return "", err
+	}
+	if n <= iSerialNumberOffset || desc[iSerialNumberOffset] == 0 {
+		return "", nil
+	}
+	buf := make([]byte, maxStringDescriptorSize)
+	n, err = dev.Control(requestTypeStandardIn, requestGetDescriptor,
+		descriptorTypeString<<8|uint16(desc[iSerialNumberOffset]), langIDEnglishUS, buf)
+	if err != nil {
+		return "", err
+	}
+	return parseStringDescriptor(buf[:n])
+}
+
+// parseStringDescriptor decodes [length, descriptorTypeString, UTF-16LE text...].
+func parseStringDescriptor(p []byte) (string, error) {
+	if len(p) < 2 || p[1] != descriptorTypeString || int(p[0]) > len(p) {
+		return "", fmt.Errorf("Malformed string descriptor: %v", p)
+	}
+	var text []uint16
+	for i := 2; i+1 < int(p[0]); i += 2 {
+		text = append(text, uint16(p[i])|uint16(p[i+1])<<8)
+	}
+	return string(utf16.Decode(text)), nil
 }
 
 type deviceInfo struct {
-	desc usb.Descriptor
+	desc   usb.Descriptor
+	serial string
 }
 
 func (d deviceInfo) Bus() int      { return int(d.desc.Bus) }
 func (d deviceInfo) Address() int  { return int(d.desc.Address) }
 func (d deviceInfo) MajorVer() int { return int((d.desc.Device >> 8) & 0xFF) }
 func (d deviceInfo) MinorVer() int { return int(d.desc.Device & 0xFF) }
+
+// Serial returns the serial number of the dongle, or an empty string
+// if its firmware does not report one. Unlike String(), it does not change
+// when the dongle is plugged into another port.
+func (d deviceInfo) Serial() string { return d.serial }
+
 func (d deviceInfo) String() string {
 	return fmt.Sprintf("CrazyRadio-Bus:%d-Address:%d-v%02x.%02x",
 		d.Bus(), d.Address(), d.MajorVer(), d.MinorVer())
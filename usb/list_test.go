@@ -0,0 +1,67 @@
+package usb
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/kylelemons/gousb/usb"
+)
+
+func TestListDevicesOpenFailure(t *testing.T) {
+	descs := []usb.Descriptor{
+		{Bus: 1, Address: 1, Vendor: Vendor, Product: Product, Device: 0x0100},
+		{Bus: 1, Address: 2, Vendor: Vendor, Product: Product, Device: 0x0100},
+		{Bus: 1, Address: 3, Vendor: Vendor, Product: Product, Device: 0x0100},
+	}
+	opened := make(map[uint8]int)
+	denied := true
+	l := &lister{
+		list: func() ([]usb.Descriptor, error) { return descs, nil },
+		serial: func(desc usb.Descriptor) (string, error) {
+			opened[desc.Address]++
+			if desc.Address == 2 && denied {
+				return "", fmt.Errorf("access denied")
+			}
+			return fmt.Sprintf("SN%d", desc.Address), nil
+		},
+		retry: time.Hour,
+		cache: make(map[string]serialEntry),
+	}
+	check := func(want ...string) {
+		t.Helper()
+		list, err := l.ListDevices()
+		if err != nil {
+			t.Fatalf("ListDevices: %v", err)
+		}
+		var got []string
+		for _, info := range list {
+			got = append(got, info.(deviceInfo).Serial())
+		}
+		if fmt.Sprintf("%q", got) != fmt.Sprintf("%q", want) {
+			t.Errorf("Unexpected serial numbers. Want: %q, got: %q", want, got)
+		}
+	}
+
+	// The dongle which can't be opened is listed without a serial number
+	check("SN1", "", "SN3")
+	// Serial numbers are cached, and the failed dongle is not opened again until the retry delay passes
+	check("SN1", "", "SN3")
+	if opened[1] != 1 || opened[2] != 1 || opened[3] != 1 {
+		t.Errorf("Each dongle must be opened once, got %v", opened)
+	}
+
+	denied = false
+	l.retry = 0
+	check("SN1", "SN2", "SN3")
+	if opened[1] != 1 || opened[2] != 2 {
+		t.Errorf("Only the failed dongle must be opened again, got %v", opened)
+	}
+
+	// Unplugged dongles are forgotten
+	descs = descs[:1]
+	check("SN1")
+	if len(l.cache) != 1 {
+		t.Errorf("Unplugged dongles must be removed from the cache: %v", l.cache)
+	}
+}
@@ -0,0 +1,104 @@
+package cflie
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// WatchOptions configure Station.Watch. Zero fields mean the defaults.
+type WatchOptions struct {
+	// Radio addresses to scan. The default is DefaultRadioAddress.
+	RadioAddrs [][5]byte
+	// Dongles to scan with
+	Dongles DongleSelector
+	// Pause between scans. The default is 5s.
+	Interval time.Duration
+	// A Crazyflie is reported lost after this many scans in a row have missed it.
+	// The default is 3.
+	Misses int
+}
+
+const (
+	defaultWatchInterval = 5 * time.Second
+	defaultWatchMisses   = 3
+)
+
+// presence tracks which Crazyflies are around, so that a single missed scan does not flap.
+type presence struct {
+	misses int
+	// Number of scans in a row which have missed each known Crazyflie
+	missed map[string]int
+}
+
+// update accounts for the scan results. It returns the addresses which have
+// just appeared and the ones which are considered lost, both sorted.
+func (p *presence) update(found []string) (appeared, lost []string) {
+	seen := make(map[string]bool)
+	for _, addr := range found {
+		seen[addr] = true
+		if _, ok := p.missed[addr]; !ok {
+			appeared = append(appeared, addr)
+		}
+		p.missed[addr] = 0
+	}
+	for addr := range p.missed {
+		if seen[addr] {
+			continue
+		}
+		p.missed[addr]++
+		if p.missed[addr] >= p.misses {
+			delete(p.missed, addr)
+			lost = append(lost, addr)
+		}
+	}
+	sort.Sort(byRadioURI(appeared))
+	sort.Sort(byRadioURI(lost))
+	return
+}
+
+func (st *station) Watch(ctx context.Context, opts WatchOptions) error {
+	if opts.RadioAddrs == nil {
+		opts.RadioAddrs = [][5]byte{DefaultRadioAddress}
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = defaultWatchInterval
+	}
+	if opts.Misses <= 0 {
+		opts.Misses = defaultWatchMisses
+	}
+	p := &presence{misses: opts.Misses, missed: make(map[string]int)}
+	timeout := st.opts.ScanChunkTimeout * time.Duration(len(Rates)*len(opts.RadioAddrs))
+	for {
+		scanCtx, cancel := context.WithTimeout(ctx, timeout)
+		found, err := st.ScanContext(scanCtx, ScanOptions{RadioAddrs: opts.RadioAddrs, Dongles: opts.Dongles, Background: true})
+		cancel()
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err == ErrStationClosed {
+			return err
+		}
+		if err != nil {
+			// It's not known who is around, e.g. if all the dongles are busy
+			st.log.Warn("Background scan failed", "err", err)
+		} else {
+			appeared, lost := p.update(found)
+			for _, addr := range appeared {
+				st.log.Info("Crazyflie found", "addr", addr)
+				st.events.emit(Event{Type: CrazyflieFound, Addr: addr})
+			}
+			for _, addr := range lost {
+				st.log.Info("Crazyflie lost", "addr", addr)
+				st.events.emit(Event{Type: CrazyflieLost, Addr: addr})
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-st.quit:
+			return ErrStationClosed
+		case <-time.After(opts.Interval):
+		}
+	}
+}